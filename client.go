@@ -2,11 +2,18 @@ package dropbox
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // Client implements a Dropbox client. You may use the Files and Users
@@ -16,21 +23,342 @@ type Client struct {
 	Users   *Users
 	Files   *Files
 	Sharing *Sharing
+	Team    *Team
+
+	FileRequests   *FileRequests
+	FileProperties *FileProperties
+	Paper          *Paper
+
+	ctx         context.Context
+	timeout     time.Duration
+	pathRoot    *PathRoot
+	selectUser  string
+	selectAdmin string
 }
 
 // New client.
 func New(config *Config) *Client {
-	c := &Client{Config: config}
+	return newClient(config, nil)
+}
+
+// newClient with the given context, which may be nil.
+func newClient(config *Config, ctx context.Context) *Client {
+	c := &Client{Config: config, ctx: ctx}
 	c.Users = &Users{c}
 	c.Files = &Files{c}
 	c.Sharing = &Sharing{c}
+	c.Team = &Team{c}
+	c.FileRequests = &FileRequests{c}
+	c.FileProperties = &FileProperties{c}
+	c.Paper = &Paper{c}
 	return c
 }
 
+// WithContext returns a copy of the client whose requests use ctx, so that
+// they are aborted when it is cancelled or its deadline passes. This
+// includes reading the body of downloads.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	if ctx == nil {
+		panic("dropbox: nil context")
+	}
+	n := c.clone()
+	n.ctx = ctx
+	return n
+}
+
+// WithTimeout returns a copy of the client whose requests must complete
+// within d, including retries and reading the body of downloads, for example
+// to fail fast on metadata calls but not on large downloads.
+func (c *Client) WithTimeout(d time.Duration) *Client {
+	n := c.clone()
+	n.timeout = d
+	return n
+}
+
+// WithPathRoot returns a copy of the client whose requests are relative to
+// root instead of the Config's PathRoot, for example to access a team space
+// for some calls only.
+func (c *Client) WithPathRoot(root *PathRoot) *Client {
+	n := c.clone()
+	n.pathRoot = root
+	return n
+}
+
+// WithSelectUser returns a copy of the client acting as the given team
+// member, instead of the Config's SelectUser.
+func (c *Client) WithSelectUser(memberID string) *Client {
+	n := c.clone()
+	n.selectUser = memberID
+	return n
+}
+
+// WithSelectAdmin returns a copy of the client acting as the given team
+// admin, instead of the Config's SelectAdmin.
+func (c *Client) WithSelectAdmin(memberID string) *Client {
+	n := c.clone()
+	n.selectAdmin = memberID
+	return n
+}
+
+// clone returns a copy of the client sharing its Config.
+func (c *Client) clone() *Client {
+	n := newClient(c.Config, c.ctx)
+	n.timeout = c.timeout
+	n.pathRoot = c.pathRoot
+	n.selectUser = c.selectUser
+	n.selectAdmin = c.selectAdmin
+	return n
+}
+
+// setHeaders adds the Config's headers to req, overriding those selected with
+// WithPathRoot, WithSelectUser and WithSelectAdmin.
+func (c *Client) setHeaders(req *http.Request) {
+	c.Config.setHeaders(req)
+
+	if isNotify(req) {
+		return
+	}
+
+	if c.pathRoot != nil {
+		root, _ := json.Marshal(c.pathRoot)
+		req.Header.Set("Dropbox-API-Path-Root", string(root))
+	}
+
+	if c.selectUser != "" {
+		req.Header.Set("Dropbox-API-Select-User", c.selectUser)
+	}
+
+	if c.selectAdmin != "" {
+		req.Header.Set("Dropbox-API-Select-Admin", c.selectAdmin)
+	}
+}
+
+// context returns the client's context, defaulting to context.Background.
+func (c *Client) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// parallel calls fn for each index up to n, with at most concurrency calls
+// running at once.
+func parallel(n, concurrency int, fn func(i int)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// scopeProbes are cheap requests requiring a single scope, used to determine
+// whether a token has been granted scopes which are not known up front. An
+// endpoint specific (409) error means the scope is present, as it is only
+// returned once the token has been authorized. None of the probes modify the
+// account: files.content.write appends to an upload session which does not
+// exist.
+var scopeProbes = map[string]func(c *Client) error{
+	"account_info.read": func(c *Client) error {
+		return discard(c.call("/users/get_current_account", nil))
+	},
+	"files.metadata.read": func(c *Client) error {
+		return discard(c.call("/files/list_folder", map[string]interface{}{"path": "", "limit": 1}))
+	},
+	"files.content.read": func(c *Client) error {
+		body, _, err := c.download("/files/download", map[string]string{"path": "/.dropbox-scope-probe"}, nil)
+		return discard(body, err)
+	},
+	"files.content.write": func(c *Client) error {
+		in := map[string]interface{}{
+			"cursor": map[string]interface{}{"session_id": "dropbox-scope-probe", "offset": 0},
+		}
+		body, _, err := c.download("/files/upload_session/append_v2", in, strings.NewReader(""))
+		return discard(body, err)
+	},
+	"sharing.read": func(c *Client) error {
+		return discard(c.call("/sharing/list_shared_links", map[string]string{}))
+	},
+}
+
+// HasScope returns whether the access token has been granted scope. The
+// Config's Scopes are used when set, otherwise a request requiring the scope
+// is made and a missing_scope error interpreted. Only the scopes
+// account_info.read, files.metadata.read, files.content.read,
+// files.content.write and sharing.read can be probed. Other failures of the
+// probe, such as an expired token or a server error, are returned.
+func (c *Client) HasScope(scope string) (bool, error) {
+	if c.Scopes != nil {
+		for _, s := range c.Scopes {
+			if s == scope {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	probe, ok := scopeProbes[scope]
+	if !ok {
+		return false, fmt.Errorf("dropbox: cannot determine whether the token has scope %q", scope)
+	}
+
+	err := probe(c)
+
+	if hasTag(err, "missing_scope") {
+		return false, nil
+	}
+
+	if e, ok := asError(err); ok && e.StatusCode == 409 {
+		return true, nil
+	}
+
+	return err == nil, err
+}
+
+// CheckInput request input.
+type CheckInput struct {
+	Query string `json:"query"`
+}
+
+// CheckOutput request output, whose Result echos the Query.
+type CheckOutput struct {
+	Result string `json:"result"`
+}
+
+// Check the access token by making an authenticated request which echos the
+// query.
+func (c *Client) Check(in *CheckInput) (out *CheckOutput, err error) {
+	body, err := c.call("/check/user", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// Ping checks connectivity and the access token, for failing fast at startup.
+// An invalid, expired or revoked token returns an *Error with a 401 status,
+// such as "invalid_access_token/..", unless it can be refreshed. Use
+// HasScope to check the token's scopes.
+func (c *Client) Ping() error {
+	const query = "ping"
+
+	out, err := c.Check(&CheckInput{Query: query})
+	if err != nil {
+		return err
+	}
+
+	if out.Result != query {
+		return fmt.Errorf("dropbox: check returned %q, expected %q", out.Result, query)
+	}
+
+	return nil
+}
+
+// AccountInfoOutput is the state of the current account.
+type AccountInfoOutput struct {
+	Account    *GetCurrentAccountOutput
+	SpaceUsage *GetSpaceUsageOutput
+
+	// RootNamespaceID is the namespace of the account's root folder, which
+	// differs from its home namespace for team members.
+	RootNamespaceID string
+}
+
+// AccountInfo returns the current account and its space usage, requested
+// concurrently. Both requests are cancelled when the client's context is
+// done or either fails.
+func (c *Client) AccountInfo() (*AccountInfoOutput, error) {
+	ctx, cancel := context.WithCancel(c.context())
+	defer cancel()
+
+	out := &AccountInfoOutput{}
+
+	var once sync.Once
+	var failed error
+
+	get := []func() error{
+		func() error {
+			body, err := c.callContext(ctx, "/users/get_current_account", nil)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			return decode(body, &out.Account)
+		},
+		func() error {
+			body, err := c.callContext(ctx, "/users/get_space_usage", nil)
+			if err != nil {
+				return err
+			}
+			defer body.Close()
+			return decode(body, &out.SpaceUsage)
+		},
+	}
+
+	parallel(len(get), len(get), func(i int) {
+		if err := get[i](); err != nil {
+			once.Do(func() {
+				failed = err
+				cancel()
+			})
+		}
+	})
+
+	if failed != nil {
+		return nil, failed
+	}
+
+	out.RootNamespaceID = out.Account.RootInfo.RootNamespaceID
+	return out, nil
+}
+
+// discard closes the body of a request whose response is not needed.
+func discard(body io.ReadCloser, err error) error {
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
 // call rpc style endpoint.
 func (c *Client) call(path string, in interface{}) (io.ReadCloser, error) {
-	url := "https://api.dropboxapi.com/2" + path
+	return c.callContext(c.context(), path, in)
+}
+
+// callContext calls an rpc style endpoint with ctx.
+func (c *Client) callContext(ctx context.Context, path string, in interface{}) (io.ReadCloser, error) {
+	return c.post(ctx, c.baseURL()+"/2"+path, in)
+}
+
+// notify style endpoint, which is not authenticated.
+func (c *Client) notify(ctx context.Context, path string, in interface{}) (io.ReadCloser, error) {
+	return c.post(context.WithValue(ctx, notifyRequest{}, true), c.notifyURL()+"/2"+path, in)
+}
+
+// contentCall calls an rpc style endpoint on the content host.
+func (c *Client) contentCall(path string, in interface{}) (io.ReadCloser, error) {
+	return c.post(c.context(), c.contentURL()+"/2"+path, in)
+}
 
+// post in as JSON to url.
+func (c *Client) post(ctx context.Context, url string, in interface{}) (io.ReadCloser, error) {
 	body, err := json.Marshal(in)
 	if err != nil {
 		return nil, err
@@ -40,67 +368,371 @@ func (c *Client) call(path string, in interface{}) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	req = req.WithContext(ctx)
+	c.setHeaders(req)
 	req.Header.Set("Content-Type", "application/json")
 
-	r, _, err := c.do(req)
-	return r, err
+	res, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return newJSONBody(res), nil
 }
 
 // download style endpoint.
 func (c *Client) download(path string, in interface{}, r io.Reader) (io.ReadCloser, int64, error) {
-	url := "https://content.dropboxapi.com/2" + path
+	res, err := c.content(path, in, r)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	body, err := json.Marshal(in)
+	return newJSONBody(res), res.ContentLength, nil
+}
+
+// maxDecodeBody is the maximum size of the start of a response body kept for
+// a DecodeError.
+const maxDecodeBody = 256
+
+// jsonBody is a response body which keeps the start of what was read, so
+// that decode can describe a body which is not the expected JSON.
+type jsonBody struct {
+	io.ReadCloser
+	status int
+	kind   string
+	head   []byte
+}
+
+// newJSONBody returns the body of res.
+func newJSONBody(res *http.Response) *jsonBody {
+	return &jsonBody{
+		ReadCloser: res.Body,
+		status:     res.StatusCode,
+		kind:       res.Header.Get("Content-Type"),
+	}
+}
+
+// Read implementation.
+func (b *jsonBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxDecodeBody - len(b.head); room > 0 {
+		if room > n {
+			room = n
+		}
+		b.head = append(b.head, p[:room]...)
+	}
+	return n, err
+}
+
+// decode the JSON body into v, returning a *DecodeError when the body is
+// empty or not the expected JSON.
+func decode(body io.Reader, v interface{}) error {
+	err := json.NewDecoder(body).Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	b, ok := body.(*jsonBody)
+	if !ok {
+		return err
+	}
+
+	return &DecodeError{
+		StatusCode:  b.status,
+		ContentType: b.kind,
+		Body:        string(b.head),
+		Err:         err,
+	}
+}
+
+// downloadResult performs a download style request, decoding the
+// Dropbox-API-Result header into result.
+func (c *Client) downloadResult(path string, in interface{}, r io.Reader, result interface{}) (io.ReadCloser, int64, error) {
+	res, err := c.content(path, in, r)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	if err := json.Unmarshal([]byte(res.Header.Get("Dropbox-API-Result")), result); err != nil {
+		res.Body.Close()
+		return nil, 0, err
+	}
+
+	return res.Body, res.ContentLength, nil
+}
+
+// content performs a download style request, returning the response.
+func (c *Client) content(path string, in interface{}, r io.Reader) (*http.Response, error) {
+	return c.contentHeader(path, in, r, nil)
+}
+
+// contentHeader performs a download style request with additional headers.
+func (c *Client) contentHeader(path string, in interface{}, r io.Reader, header http.Header) (*http.Response, error) {
+	return c.contentRequest(c.contentURL()+"/2"+path, in, r, header)
+}
+
+// apiContent performs a download style request to an endpoint on the api
+// host rather than the content host, such as those of Paper.
+func (c *Client) apiContent(path string, in interface{}) (*http.Response, error) {
+	return c.contentRequest(c.baseURL()+"/2"+path, in, nil, nil)
+}
+
+// contentRequest performs a download style request to url, with the
+// arguments in the Dropbox-API-Arg header and r as the body.
+func (c *Client) contentRequest(url string, in interface{}, r io.Reader, header http.Header) (*http.Response, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequest("POST", url, r)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	req = req.WithContext(c.context())
+	c.setHeaders(req)
+	req.Header.Set("Dropbox-API-Arg", headerArg(body))
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if s, ok := r.(io.ReadSeeker); ok && req.GetBody == nil {
+		if req.GetBody, err = seekBody(s); err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(s)
 	}
-	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
-	req.Header.Set("Dropbox-API-Arg", string(body))
 
 	if r != nil {
 		req.Header.Set("Content-Type", "application/octet-stream")
+
+		if req.ContentLength == 0 {
+			if n, err := readerSize(r); err == nil && n > 0 {
+				req.ContentLength = n
+			}
+		}
 	}
 
 	return c.do(req)
 }
 
-// perform the request.
-func (c *Client) do(req *http.Request) (io.ReadCloser, int64, error) {
-	res, err := c.HTTPClient.Do(req)
+// headerArg returns the JSON argument b escaped for the Dropbox-API-Arg
+// header, which must be ASCII, so a path such as "/café.txt" is sent as
+// "/caf\u00e9.txt".
+func headerArg(b []byte) string {
+	var buf strings.Builder
+
+	for _, r := range string(b) {
+		switch {
+		case r < utf8.RuneSelf && r != 0x7f:
+			buf.WriteRune(r)
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(&buf, "\\u%04x\\u%04x", r1, r2)
+		default:
+			fmt.Fprintf(&buf, "\\u%04x", r)
+		}
+	}
+
+	return buf.String()
+}
+
+// seekBody returns a GetBody function for an http.Request, which rewinds r to
+// its current offset so that the body can be replayed on retry. The request
+// body must also be wrapped with ioutil.NopCloser, as the transport closes it
+// after each attempt, which would prevent seeking a file.
+func seekBody(r io.ReadSeeker) (func() (io.ReadCloser, error), error) {
+	start, err := r.Seek(0, io.SeekCurrent)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+
+	return func() (io.ReadCloser, error) {
+		if _, err := r.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(r), nil
+	}, nil
+}
+
+// perform the request, retrying rate limited and failed requests, and those
+// failing because the access token expired once it has been refreshed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.DryRun {
+		return nil, dryRun(req)
+	}
+
+	start := time.Now()
+	res, err := c.doTimeout(req)
+	c.observe(req, start, res, err)
+	return res, err
+}
+
+// observe notifies the Observer of the outcome of req.
+func (c *Client) observe(req *http.Request, start time.Time, res *http.Response, err error) {
+	if c.Observer == nil {
+		return
 	}
 
+	status := 0
+	if res != nil {
+		status = res.StatusCode
+	} else if e, ok := asError(err); ok {
+		status = e.StatusCode
+	}
+
+	c.Observer.ObserveCall(strings.TrimPrefix(req.URL.Path, "/2"), time.Since(start), status, err)
+}
+
+// doTimeout performs the request, within the client's timeout when set.
+func (c *Client) doTimeout(req *http.Request) (*http.Response, error) {
+	if c.timeout <= 0 {
+		return c.retry(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+	res, err := c.retry(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	res.Body = &cancelBody{res.Body, cancel}
+	return res, nil
+}
+
+// cancelBody cancels the context of a request when its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implementation.
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retry sends req, refreshing the access token when it has expired, and
+// retrying rate limited and failed requests up to MaxRetries times.
+func (c *Client) retry(req *http.Request) (*http.Response, error) {
+	refreshed := false
+
+	for attempt := 1; ; attempt++ {
+		res, err := c.send(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode == 401 && !refreshed && c.canRefresh() && replayable(req) {
+			_, err := c.response(res)
+			if !hasTag(err, "expired_access_token") {
+				return nil, err
+			}
+
+			if err := c.refresh(req, strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")); err != nil {
+				return nil, err
+			}
+
+			if req, err = rewind(req); err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+c.accessToken())
+
+			refreshed = true
+			attempt--
+			continue
+		}
+
+		if attempt > c.MaxRetries || !retryable(req, res) {
+			return c.response(res)
+		}
+
+		delay := c.retryDelay(res, attempt)
+
+		if _, err := c.response(res); IsTooManyWriteOperations(err) {
+			delay += jitter(delay)
+		} else if res.StatusCode == 409 {
+			return nil, err
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewind returns a copy of req whose body can be sent again.
+func rewind(req *http.Request) (*http.Request, error) {
+	req = req.Clone(req.Context())
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+
+	return req, nil
+}
+
+// retryable returns whether req may be retried after res. A 409 is only
+// retried when it reports too_many_write_operations.
+func retryable(req *http.Request, res *http.Response) bool {
+	if res.StatusCode != 429 && res.StatusCode != 409 && res.StatusCode < 500 {
+		return false
+	}
+
+	return replayable(req)
+}
+
+// jitter returns a random duration of up to half of d, so that concurrent
+// writers retrying together are spread out.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)/2 + 1))
+}
+
+// replayable returns whether the body of req can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// response returns res, or an *Error if it failed.
+func (c *Client) response(res *http.Response) (*http.Response, error) {
 	if res.StatusCode < 400 {
-		return res.Body, res.ContentLength, err
+		return res, nil
 	}
 
 	defer res.Body.Close()
 
+	b, err := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	if err != nil {
+		return nil, err
+	}
+
 	e := &Error{
 		Status:     http.StatusText(res.StatusCode),
 		StatusCode: res.StatusCode,
+		Body:       b,
 	}
 
 	kind := res.Header.Get("Content-Type")
 
-	if strings.Contains(kind, "text/plain") {
-		if b, err := ioutil.ReadAll(res.Body); err == nil {
-			e.Summary = string(b)
-			return nil, 0, e
-		}
-		return nil, 0, err
-	}
-
-	if err := json.NewDecoder(res.Body).Decode(e); err != nil {
-		return nil, 0, err
+	if strings.Contains(kind, "text/plain") || json.Unmarshal(b, e) != nil {
+		e.Summary = string(b)
 	}
 
-	return nil, 0, e
+	return nil, e
 }
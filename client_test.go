@@ -1,7 +1,16 @@
 package dropbox
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/segmentio/go-env"
 	"github.com/stretchr/testify/assert"
@@ -12,6 +21,51 @@ func client() *Client {
 	return New(NewConfig(token))
 }
 
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implementation.
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// mockClient returns a client whose requests are served by handler.
+func mockClient(handler http.HandlerFunc) *Client {
+	config := NewConfig("token")
+	config.MaxRetries = 0
+	config.HTTPClient = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if err := r.Context().Err(); err != nil {
+				return nil, err
+			}
+			return w.Result(), nil
+		}),
+	}
+	return New(config)
+}
+
+// writeJSON responds with v encoded as JSON.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with a Dropbox style error summary.
+func writeError(w http.ResponseWriter, status int, summary string) {
+	writeJSON(w, status, map[string]string{"error_summary": summary})
+}
+
+// decodeArg decodes the request arguments of an rpc or content style request.
+func decodeArg(r *http.Request, v interface{}) error {
+	if arg := r.Header.Get("Dropbox-API-Arg"); arg != "" {
+		return json.Unmarshal([]byte(arg), v)
+	}
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
 func TestClient_error_text(t *testing.T) {
 	c := client()
 
@@ -30,7 +84,7 @@ func TestClient_error_text(t *testing.T) {
 func TestClient_error_json(t *testing.T) {
 	c := client()
 
-	_, err := c.Files.Download(&DownloadInput{"/nothing"})
+	_, err := c.Files.Download(&DownloadInput{Path: "/nothing"})
 	assert.Error(t, err)
 
 	e := err.(*Error)
@@ -38,3 +92,593 @@ func TestClient_error_json(t *testing.T) {
 	assert.Equal(t, "Conflict", e.Status)
 	assert.Equal(t, 409, e.StatusCode)
 }
+
+func TestClient_HasScope(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/list_folder":
+			writeJSON(w, 200, map[string]interface{}{"entries": []interface{}{}})
+		case "/2/files/download":
+			writeError(w, 409, "path/not_found/..")
+		default:
+			writeError(w, 401, "missing_scope/.")
+		}
+	})
+
+	ok, err := c.HasScope("files.metadata.read")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.HasScope("files.content.read")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.HasScope("sharing.read")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = c.HasScope("team_data.member")
+	assert.Error(t, err)
+}
+
+func TestClient_HasScope_errors(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/users/get_current_account":
+			writeError(w, 401, "expired_access_token/..")
+		case "/2/files/list_folder":
+			writeError(w, 500, "internal_error/..")
+		case "/2/files/upload_session/append_v2":
+			writeError(w, 409, "not_found/..")
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	ok, err := c.HasScope("account_info.read")
+	assert.True(t, hasTag(err, "expired_access_token"))
+	assert.False(t, ok)
+
+	ok, err = c.HasScope("files.metadata.read")
+	assert.Equal(t, 500, err.(*Error).StatusCode)
+	assert.False(t, ok)
+
+	ok, err = c.HasScope("files.content.write")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestClient_HasScope_known(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+	c.Scopes = []string{"files.metadata.read", "team_data.member"}
+
+	ok, err := c.HasScope("team_data.member")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c.HasScope("files.content.write")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestClient_AccountInfo(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/users/get_current_account":
+			writeJSON(w, 200, map[string]interface{}{
+				"account_id": "dbid:1",
+				"email":      "tobi@example.com",
+				"root_info":  map[string]string{".tag": "team", "root_namespace_id": "100", "home_namespace_id": "200"},
+			})
+		case "/2/users/get_space_usage":
+			writeJSON(w, 200, map[string]interface{}{
+				"used":       10,
+				"allocation": map[string]interface{}{".tag": "individual", "allocated": 100},
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	out, err := c.AccountInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "dbid:1", out.Account.AccountID)
+	assert.Equal(t, "tobi@example.com", out.Account.Email)
+	assert.Equal(t, uint64(10), out.SpaceUsage.Used)
+	assert.Equal(t, uint64(100), out.SpaceUsage.Allocation.Allocated)
+	assert.Equal(t, "100", out.RootNamespaceID)
+}
+
+func TestClient_AccountInfo_error(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2/users/get_space_usage" {
+			writeError(w, 401, "missing_scope/.")
+			return
+		}
+		<-r.Context().Done()
+	})
+
+	_, err := c.AccountInfo()
+	assert.True(t, hasTag(err, "missing_scope"))
+}
+
+func TestClient_retry(t *testing.T) {
+	var attempts []string
+	var waits []time.Duration
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		attempts = append(attempts, string(body))
+
+		switch len(attempts) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			writeError(w, 429, "too_many_requests/..")
+		case 2:
+			writeError(w, 503, "unavailable")
+		default:
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+		}
+	})
+	c.MaxRetries = 2
+	c.RetryBackoff = func(attempt int) time.Duration {
+		waits = append(waits, time.Duration(attempt)*time.Millisecond)
+		return time.Millisecond
+	}
+
+	out, err := c.Files.Upload(&UploadInput{
+		Path:   "/a.txt",
+		Reader: &onlyReadSeeker{strings.NewReader("hello")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt", out.PathLower)
+	assert.Equal(t, []string{"hello", "hello", "hello"}, attempts)
+	assert.Equal(t, []time.Duration{2 * time.Millisecond}, waits)
+
+	attempts = nil
+	_, err = c.Files.Upload(&UploadInput{
+		Path:   "/a.txt",
+		Reader: ioutil.NopCloser(strings.NewReader("hello")),
+	})
+	assert.True(t, IsRateLimited(err))
+	assert.Len(t, attempts, 1, "unreplayable bodies are not retried")
+
+	attempts = nil
+	c.MaxRetries = 1
+	_, err = c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.Equal(t, 503, err.(*Error).StatusCode)
+	assert.Len(t, attempts, 2)
+}
+
+func TestClient_retry_tooManyWriteOperations(t *testing.T) {
+	attempts := 0
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch {
+		case r.URL.Path == "/2/files/create_folder":
+			writeError(w, 409, "path/conflict/folder/..")
+		case attempts == 1:
+			writeError(w, 409, "path/too_many_write_operations/..")
+		case attempts == 2:
+			w.Header().Set("Retry-After", "0")
+			writeError(w, 429, "too_many_write_operations/..")
+		default:
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+		}
+	})
+	c.MaxRetries = 2
+	c.RetryBackoff = func(int) time.Duration { return time.Millisecond }
+
+	out, err := c.Files.Upload(&UploadInput{Path: "/a.txt", Reader: strings.NewReader("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt", out.PathLower)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	_, err = c.Files.CreateFolder(&CreateFolderInput{Path: "/a"})
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsTooManyWriteOperations(err))
+	assert.Equal(t, 1, attempts, "other conflicts are not retried")
+}
+
+// onlyReadSeeker hides all but the io.ReadSeeker methods of a reader.
+type onlyReadSeeker struct {
+	io.ReadSeeker
+}
+
+func TestClient_retry_file(t *testing.T) {
+	f, err := ioutil.TempFile("", "dropbox")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.WriteString("hello")
+	assert.NoError(t, err)
+	_, err = f.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+
+	var attempts []string
+
+	c := New(NewConfig("token"))
+	c.RetryBackoff = func(int) time.Duration { return time.Millisecond }
+	c.HTTPClient = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			attempts = append(attempts, string(body))
+
+			w := httptest.NewRecorder()
+			if len(attempts) == 1 {
+				writeError(w, 429, "too_many_requests/..")
+			} else {
+				writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+			}
+			return w.Result(), nil
+		}),
+	}
+
+	out, err := c.Files.Upload(&UploadInput{Path: "/a.txt", Reader: f})
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt", out.PathLower)
+	assert.Equal(t, []string{"hello", "hello"}, attempts)
+}
+
+func TestClient_WithContext(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]string{".tag": "file"})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := c.WithContext(ctx).Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = c.Files.WithContext(ctx).GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.NoError(t, err, "original client is unaffected")
+}
+
+func TestClient_WithContext_download(t *testing.T) {
+	c := New(NewConfig("token"))
+	c.HTTPClient = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			pr, pw := io.Pipe()
+			go func() {
+				<-r.Context().Done()
+				pw.CloseWithError(r.Context().Err())
+			}()
+			return &http.Response{StatusCode: 200, Body: pr, Header: http.Header{}}, nil
+		}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out, err := c.Files.WithContext(ctx).Download(&DownloadInput{Path: "/big.bin"})
+	assert.NoError(t, err)
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = ioutil.ReadAll(out.Body)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClient_WithTimeout(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2/files/get_metadata" {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("hello"))
+	})
+
+	_, err := c.WithTimeout(10 * time.Millisecond).Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	out, err := c.WithTimeout(time.Minute).Files.Download(&DownloadInput{Path: "/a.txt"})
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	assert.NoError(t, out.Body.Close())
+}
+
+func TestClient_AccountInfo_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-r.Context().Done()
+	})
+
+	_, err := c.WithContext(ctx).AccountInfo()
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClient_defaultHTTPClient(t *testing.T) {
+	var hosts []string
+
+	transport := http.DefaultClient.Transport
+	defer func() { http.DefaultClient.Transport = transport }()
+
+	http.DefaultClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hosts = append(hosts, r.URL.Host)
+		w := httptest.NewRecorder()
+		writeJSON(w, 200, map[string]string{".tag": "file"})
+		return w.Result(), nil
+	})
+
+	c := New(&Config{AccessToken: "token"})
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api.dropboxapi.com"}, hosts)
+}
+
+func TestClient_refreshToken(t *testing.T) {
+	var auth []string
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+			assert.Equal(t, "refresh", r.Form.Get("refresh_token"))
+			assert.Equal(t, "key", r.Form.Get("client_id"))
+			assert.Equal(t, "secret", r.Form.Get("client_secret"))
+			writeJSON(w, 200, map[string]interface{}{"access_token": "new", "expires_in": 14400})
+			return
+		}
+
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(b), `"path":"/a.txt"`, "body is replayed")
+
+		auth = append(auth, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer new" {
+			writeError(w, 401, "expired_access_token/..")
+			return
+		}
+		writeJSON(w, 200, map[string]string{".tag": "file", "name": "a.txt"})
+	})
+	c.RefreshToken = "refresh"
+	c.AppKey = "key"
+	c.AppSecret = "secret"
+
+	var refreshed string
+	var expires time.Time
+	c.OnTokenRefresh = func(token string, t time.Time) {
+		refreshed, expires = token, t
+	}
+
+	out, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a.txt", out.Name)
+	assert.Equal(t, []string{"Bearer token", "Bearer new"}, auth)
+	assert.Equal(t, "new", c.AccessToken)
+	assert.Equal(t, "new", refreshed)
+	assert.WithinDuration(t, time.Now().Add(4*time.Hour), expires, time.Minute)
+}
+
+func TestClient_refreshToken_invalid(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/token" {
+			writeJSON(w, 400, map[string]string{"error": "invalid_grant", "error_description": "refresh token is invalid or revoked"})
+			return
+		}
+		writeError(w, 401, "expired_access_token/..")
+	})
+	c.RefreshToken = "refresh"
+	c.AppKey = "key"
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.EqualError(t, err, "dropbox: refreshing access token: invalid_grant: refresh token is invalid or revoked")
+}
+
+func TestClient_OnRequest(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, 409, "path/not_found/..")
+	})
+
+	var logs []*RequestLog
+	c.OnRequest = func(l *RequestLog) {
+		logs = append(logs, l)
+	}
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.True(t, IsNotFound(err), "error body is still decoded")
+
+	_, err = c.Files.Download(&DownloadInput{Path: "/a.txt"})
+	assert.True(t, IsNotFound(err))
+
+	assert.Len(t, logs, 2)
+
+	l := logs[0]
+	assert.Equal(t, "POST", l.Method)
+	assert.Equal(t, "https://api.dropboxapi.com/2/files/get_metadata", l.URL)
+	assert.Equal(t, "Bearer REDACTED", l.Header.Get("Authorization"))
+	assert.Contains(t, string(l.Body), `"path":"/a.txt"`)
+	assert.Equal(t, 409, l.StatusCode)
+	assert.Equal(t, "application/json", l.ResponseHeader.Get("Content-Type"))
+	assert.Contains(t, string(l.ResponseBody), "path/not_found")
+
+	l = logs[1]
+	assert.Equal(t, "https://content.dropboxapi.com/2/files/download", l.URL)
+	assert.Equal(t, `{"path":"/a.txt"}`, l.Header.Get("Dropbox-API-Arg"))
+	assert.Nil(t, l.Body)
+	assert.Equal(t, "Bearer REDACTED", l.Header.Get("Authorization"))
+}
+
+func TestClient_selectUser(t *testing.T) {
+	var got http.Header
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		writeJSON(w, 200, map[string]string{".tag": "folder"})
+	})
+	c.SelectUser = "dbmid:member"
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "dbmid:member", got.Get("Dropbox-API-Select-User"))
+	assert.Empty(t, got.Get("Dropbox-API-Select-Admin"))
+
+	_, err = c.WithSelectAdmin("dbmid:admin").WithSelectUser("dbmid:other").Files.GetMetadata(&GetMetadataInput{Path: "/a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "dbmid:other", got.Get("Dropbox-API-Select-User"))
+	assert.Equal(t, "dbmid:admin", got.Get("Dropbox-API-Select-Admin"))
+
+	_, err = c.WithSelectUser("dbmid:other").Files.ListFolderLongpoll(&ListFolderLongpollInput{Cursor: "c"})
+	assert.NoError(t, err)
+	assert.Empty(t, got.Get("Dropbox-API-Select-User"), "notify requests are not authorized")
+}
+
+func TestClient_headerArg(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		arg := r.Header.Get("Dropbox-API-Arg")
+		assert.Contains(t, arg, `"/caf\u00e9 \ud83d\ude00.txt"`)
+
+		var in DownloadInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/café 😀.txt", in.Path)
+
+		w.Write([]byte("hello"))
+	})
+
+	_, err := c.Files.Download(&DownloadInput{Path: "/café 😀.txt"})
+	assert.NoError(t, err)
+}
+
+func TestClient_Ping(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/check/user", r.URL.Path)
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			writeError(w, 401, "invalid_access_token/")
+			return
+		}
+
+		var in CheckInput
+		assert.NoError(t, decodeArg(r, &in))
+		writeJSON(w, 200, CheckOutput{Result: in.Query})
+	})
+
+	assert.NoError(t, c.Ping())
+
+	c.AccessToken = "revoked"
+	err := c.Ping()
+	assert.Equal(t, 401, err.(*Error).StatusCode)
+	assert.Equal(t, "invalid_access_token", err.(*Error).Tag())
+}
+
+func TestConfig_BaseURL(t *testing.T) {
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+
+		switch r.URL.Path {
+		case "/2/files/get_metadata":
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+		case "/2/files/download":
+			w.Header().Set("Dropbox-API-Result", `{".tag": "file", "path_lower": "/a.txt"}`)
+			w.Write([]byte("hello"))
+		case "/2/files/list_folder/longpoll":
+			writeJSON(w, 200, map[string]interface{}{"changes": true})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	config := NewConfig("token")
+	config.BaseURL = server.URL
+	config.ContentURL = server.URL + "/"
+	config.NotifyURL = server.URL
+	c := New(config)
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.NoError(t, err)
+
+	out, err := c.Files.Download(&DownloadInput{Path: "/a.txt"})
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(out.Body)
+	out.Body.Close()
+	assert.Equal(t, "hello", string(b))
+
+	_, err = c.Files.ListFolderLongpoll(&ListFolderLongpollInput{Cursor: "cursor"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Bearer token", "Bearer token", ""}, auth)
+}
+
+func TestClient_decodeError(t *testing.T) {
+	var body string
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		body = ""
+		_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+		assert.EqualError(t, err, "dropbox: decoding 200 response: empty body")
+
+		e, ok := err.(*DecodeError)
+		assert.True(t, ok)
+		assert.Equal(t, io.EOF, e.Err)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		body = "<html>Bad Gateway</html>"
+		_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+		assert.EqualError(t, err, `dropbox: decoding 200 response: invalid character '<' looking for beginning of value: "<html>Bad Gateway</html>"`)
+
+		e, ok := err.(*DecodeError)
+		assert.True(t, ok)
+		assert.Equal(t, "text/html", e.ContentType)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		body = `{".tag": "file", "path_lo`
+		_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+		assert.EqualError(t, err, `dropbox: decoding 200 response: unexpected EOF: "{\".tag\": \"file\", \"path_lo"`)
+	})
+}
+
+func TestClient_Observer(t *testing.T) {
+	attempts := 0
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch {
+		case r.URL.Path == "/2/files/download":
+			writeError(w, 409, "path/not_found/..")
+		case attempts == 1:
+			writeError(w, 500, "internal error")
+		default:
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+		}
+	})
+	c.MaxRetries = 1
+	c.RetryBackoff = func(int) time.Duration { return 0 }
+
+	type call struct {
+		path   string
+		status int
+		err    error
+	}
+
+	var calls []call
+	c.Observer = ObserverFunc(func(path string, d time.Duration, status int, err error) {
+		assert.True(t, d > 0)
+		calls = append(calls, call{path, status, err})
+	})
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.NoError(t, err)
+
+	_, err = c.Files.Download(&DownloadInput{Path: "/a.txt"})
+	assert.True(t, IsNotFound(err))
+
+	assert.Equal(t, []call{
+		{"/files/get_metadata", 200, nil},
+		{"/files/download", 409, err},
+	}, calls, "retries are observed as one call")
+}
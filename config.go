@@ -2,12 +2,101 @@ package dropbox
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Config for the Dropbox clients.
 type Config struct {
-	HTTPClient  *http.Client
+	// HTTPClient used for requests, such as one with a timeout or an
+	// instrumented Transport. Defaults to http.DefaultClient when nil.
+	HTTPClient *http.Client
+
 	AccessToken string
+	UserAgent   string
+
+	// RefreshToken, AppKey and AppSecret are used to obtain a new
+	// AccessToken when a request fails because the short-lived token has
+	// expired, after which the request is retried. AppSecret may be empty
+	// for tokens obtained with PKCE.
+	RefreshToken string
+	AppKey       string
+	AppSecret    string
+
+	// OnTokenRefresh is called with the new access token and its expiry
+	// after a refresh, so that it can be persisted.
+	OnTokenRefresh func(accessToken string, expires time.Time)
+
+	// PathRoot selects the namespace paths are relative to, when set.
+	PathRoot *PathRoot
+
+	// SelectUser and SelectAdmin are the team member IDs sent as the
+	// Dropbox-API-Select-User and Dropbox-API-Select-Admin headers, so that a
+	// team app acts as that member, or as an admin with access to team
+	// content, respectively.
+	SelectUser  string
+	SelectAdmin string
+
+	// HomePath is the path of the user's home folder within their team's
+	// root namespace, as returned in the RootInfo of GetCurrentAccount. When
+	// set CheckPath can detect root-relative paths used without a PathRoot.
+	HomePath string
+
+	// Scopes granted to the access token, when known, such as those returned
+	// alongside it by the OAuth2 token endpoint.
+	Scopes []string
+
+	// MaxRetries is the number of times a request failing with a 429 or 5xx
+	// status, or too_many_write_operations, is retried, waiting for the
+	// Retry-After duration when given. Requests whose body cannot be replayed
+	// are not retried. Zero disables retries.
+	MaxRetries int
+
+	// RetryBackoff returns how long to wait before the given retry attempt,
+	// starting from 1, when the response has no Retry-After header. Defaults
+	// to doubling from one second.
+	RetryBackoff func(attempt int) time.Duration
+
+	// OnRequest is called after each attempt of a request, for tracing.
+	OnRequest func(*RequestLog)
+
+	// Observer is notified once each request completes, including its
+	// retries, for metrics.
+	Observer Observer
+
+	// DryRun prevents requests from being sent, instead failing each with a
+	// *DryRunError describing it, to test how requests are constructed.
+	DryRun bool
+
+	// BaseURL, ContentURL and NotifyURL override the API, content and notify
+	// hosts, such as with the URL of an httptest.Server. They default to
+	// https://api.dropboxapi.com, https://content.dropboxapi.com and
+	// https://notify.dropboxapi.com.
+	BaseURL    string
+	ContentURL string
+	NotifyURL  string
+
+	mu sync.Mutex
+}
+
+// Observer is notified of the outcome of requests, such as to record the
+// latency and errors of each endpoint.
+type Observer interface {
+	// ObserveCall is called with the endpoint's path, such as
+	// "/files/upload", the time until the response headers were received,
+	// including any retries, and the status code of the last attempt, or
+	// zero when no response was received.
+	ObserveCall(path string, duration time.Duration, statusCode int, err error)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(path string, duration time.Duration, statusCode int, err error)
+
+// ObserveCall implementation.
+func (f ObserverFunc) ObserveCall(path string, duration time.Duration, statusCode int, err error) {
+	f(path, duration, statusCode, err)
 }
 
 // NewConfig with the given access token.
@@ -15,5 +104,50 @@ func NewConfig(accessToken string) *Config {
 	return &Config{
 		HTTPClient:  http.DefaultClient,
 		AccessToken: accessToken,
+		MaxRetries:  3,
 	}
 }
+
+// baseURL returns the BaseURL, defaulting to the API host.
+func (c *Config) baseURL() string {
+	return urlOr(c.BaseURL, "https://api.dropboxapi.com")
+}
+
+// contentURL returns the ContentURL, defaulting to the content host.
+func (c *Config) contentURL() string {
+	return urlOr(c.ContentURL, "https://content.dropboxapi.com")
+}
+
+// notifyURL returns the NotifyURL, defaulting to the notify host.
+func (c *Config) notifyURL() string {
+	return urlOr(c.NotifyURL, "https://"+notifyHost)
+}
+
+// urlOr returns u without a trailing slash, or def when it is empty.
+func urlOr(u, def string) string {
+	if u == "" {
+		return def
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// httpClient returns the HTTPClient, defaulting to http.DefaultClient.
+func (c *Config) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// retryDelay returns how long to wait before retrying after res.
+func (c *Config) retryDelay(res *http.Response, attempt int) time.Duration {
+	if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	if c.RetryBackoff != nil {
+		return c.RetryBackoff(attempt)
+	}
+
+	return time.Second << uint(attempt-1)
+}
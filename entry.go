@@ -0,0 +1,113 @@
+package dropbox
+
+import "time"
+
+// Entry is a file, folder or deleted entry, as returned by Metadata.Entry.
+// Use a type switch on *FileMetadata, *FolderMetadata and *DeletedMetadata to
+// access the fields which are valid for each.
+type Entry interface {
+	// Tag is "file", "folder" or "deleted".
+	Tag() string
+}
+
+// FileMetadata for a file.
+type FileMetadata struct {
+	Name           string
+	PathLower      string
+	PathDisplay    string
+	ID             string
+	ClientModified time.Time
+	ServerModified time.Time
+	Rev            string
+	Size           uint64
+	ContentHash    string
+	MediaInfo      *MediaInfo
+	SharingInfo    *FileSharingInfo
+	PropertyGroups []*PropertyGroup
+	IsDownloadable bool
+	ExportInfo     *ExportInfo
+	FileLockInfo   *FileLockInfo
+
+	HasExplicitSharedMembers bool
+}
+
+// Tag implementation.
+func (m *FileMetadata) Tag() string { return "file" }
+
+// FolderMetadata for a folder.
+type FolderMetadata struct {
+	Name           string
+	PathLower      string
+	PathDisplay    string
+	ID             string
+	SharingInfo    *FileSharingInfo
+	PropertyGroups []*PropertyGroup
+}
+
+// Tag implementation.
+func (m *FolderMetadata) Tag() string { return "folder" }
+
+// DeletedMetadata for a deleted file or folder.
+type DeletedMetadata struct {
+	Name        string
+	PathLower   string
+	PathDisplay string
+}
+
+// Tag implementation.
+func (m *DeletedMetadata) Tag() string { return "deleted" }
+
+// Entry returns m as the concrete type for its Tag, or nil if the tag is
+// not recognised.
+func (m *Metadata) Entry() Entry {
+	switch m.Tag {
+	case "file":
+		return &FileMetadata{
+			Name:                     m.Name,
+			PathLower:                m.PathLower,
+			PathDisplay:              m.PathDisplay,
+			ID:                       m.ID,
+			ClientModified:           m.ClientModified,
+			ServerModified:           m.ServerModified,
+			Rev:                      m.Rev,
+			Size:                     m.Size,
+			ContentHash:              m.ContentHash,
+			MediaInfo:                m.MediaInfo,
+			SharingInfo:              m.SharingInfo,
+			PropertyGroups:           m.PropertyGroups,
+			IsDownloadable:           m.IsDownloadable,
+			ExportInfo:               m.ExportInfo,
+			FileLockInfo:             m.FileLockInfo,
+			HasExplicitSharedMembers: m.HasExplicitSharedMembers,
+		}
+	case "folder":
+		return &FolderMetadata{
+			Name:           m.Name,
+			PathLower:      m.PathLower,
+			PathDisplay:    m.PathDisplay,
+			ID:             m.ID,
+			SharingInfo:    m.SharingInfo,
+			PropertyGroups: m.PropertyGroups,
+		}
+	case "deleted":
+		return &DeletedMetadata{
+			Name:        m.Name,
+			PathLower:   m.PathLower,
+			PathDisplay: m.PathDisplay,
+		}
+	default:
+		return nil
+	}
+}
+
+// TypedEntries returns the Entries as their concrete types, skipping any
+// with an unrecognised tag.
+func (out *ListFolderOutput) TypedEntries() []Entry {
+	entries := make([]Entry, 0, len(out.Entries))
+	for _, m := range out.Entries {
+		if e := m.Entry(); e != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
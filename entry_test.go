@@ -0,0 +1,38 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListFolderOutput_TypedEntries(t *testing.T) {
+	var out ListFolderOutput
+	assert.NoError(t, json.Unmarshal([]byte(`{"entries": [
+		{".tag": "file", "name": "a.txt", "path_lower": "/a.txt", "rev": "a1", "size": 5},
+		{".tag": "folder", "name": "b", "path_lower": "/b", "id": "id:b"},
+		{".tag": "deleted", "name": "c.txt", "path_lower": "/c.txt"},
+		{".tag": "unknown"}
+	]}`), &out))
+
+	entries := out.TypedEntries()
+	assert.Len(t, entries, 3)
+
+	for _, e := range entries {
+		switch m := e.(type) {
+		case *FileMetadata:
+			assert.Equal(t, "file", m.Tag())
+			assert.Equal(t, "a1", m.Rev)
+			assert.Equal(t, uint64(5), m.Size)
+		case *FolderMetadata:
+			assert.Equal(t, "folder", m.Tag())
+			assert.Equal(t, "id:b", m.ID)
+		case *DeletedMetadata:
+			assert.Equal(t, "deleted", m.Tag())
+			assert.Equal(t, "/c.txt", m.PathLower)
+		default:
+			t.Errorf("unexpected entry %T", e)
+		}
+	}
+}
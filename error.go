@@ -1,13 +1,190 @@
 package dropbox
 
-// Error response.
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrContentHashMismatch is returned when downloaded content does not match
+// the content hash reported by Dropbox.
+var ErrContentHashMismatch = errors.New("dropbox: content hash mismatch")
+
+// ErrNotVisible is returned by WaitVisible when an entry does not appear in
+// its folder's listing in time.
+var ErrNotVisible = errors.New("dropbox: entry not visible in listing")
+
+// ErrSharedLinkNotFound is returned by RevokeSharedLink when the link does
+// not exist, or was already revoked.
+var ErrSharedLinkNotFound = errors.New("dropbox: shared link not found")
+
+// ErrEmptyCursor is returned when continuing a listing without a cursor.
+var ErrEmptyCursor = errors.New("dropbox: empty cursor")
+
+// ErrCursorReset is returned when continuing or longpolling a listing whose
+// cursor Dropbox has invalidated, so the folder must be listed again from
+// scratch.
+var ErrCursorReset = errors.New("dropbox: cursor reset")
+
+// ErrJobTimeout is returned when an asynchronous job does not complete
+// within the PollOptions Timeout.
+var ErrJobTimeout = errors.New("dropbox: job timed out")
+
+// maxErrorBody is the maximum size of an error response body that is read.
+const maxErrorBody = 64 << 10
+
+// Error response. When the body is not a JSON error, such as the text/plain
+// errors of bad requests or an HTML page from a proxy, the Summary is the
+// body itself.
 type Error struct {
 	Status     string
 	StatusCode int
-	Summary    string `json:"error_summary"`
+	Summary    string          `json:"error_summary"`
+	Details    json.RawMessage `json:"error,omitempty"`
+
+	// Body is the raw response body, up to 64KB, for debugging.
+	Body []byte `json:"-"`
 }
 
 // Error string.
 func (e *Error) Error() string {
 	return e.Summary
 }
+
+// Tag returns the ".tag" of the error union, such as "path", falling back to
+// the first segment of the summary when there are no details.
+func (e *Error) Tag() string {
+	var v struct {
+		Tag string `json:".tag"`
+	}
+
+	if len(e.Details) > 0 && json.Unmarshal(e.Details, &v) == nil && v.Tag != "" {
+		return v.Tag
+	}
+
+	return strings.SplitN(e.Summary, "/", 2)[0]
+}
+
+// IsNotFound returns true if err reports that a path does not exist, such as
+// "path/not_found" or "from_lookup/not_found".
+func IsNotFound(err error) bool {
+	return hasSegment(err, "not_found")
+}
+
+// IsConflict returns true if err reports a conflict with an existing file or
+// folder, such as "path/conflict/file".
+func IsConflict(err error) bool {
+	return hasSegment(err, "conflict")
+}
+
+// IsRateLimited returns true if err reports too many requests, or too many
+// concurrent writes to the same namespace. Such requests may be retried
+// after a delay.
+func IsRateLimited(err error) bool {
+	e, ok := asError(err)
+	if !ok {
+		return false
+	}
+
+	return e.StatusCode == 429 || hasTag(err, "too_many_requests") || IsTooManyWriteOperations(err)
+}
+
+// IsTooManyWriteOperations returns true if err reports too many concurrent
+// writes to the same namespace, as a 429 or as the reason a write failed.
+// Such writes are retried with jitter up to the Config's MaxRetries.
+func IsTooManyWriteOperations(err error) bool {
+	return hasSegment(err, "too_many_write_operations")
+}
+
+// IsCursorReset returns true if err reports that a listing's cursor was
+// reset, as ErrCursorReset or an *Error with the "reset" tag.
+func IsCursorReset(err error) bool {
+	return err == ErrCursorReset || hasTag(err, "reset")
+}
+
+// IsOwnershipTransferError returns true if err reports that a move would
+// transfer ownership of the content, or that the transfer failed because
+// the destination account lacks the quota to take ownership.
+func IsOwnershipTransferError(err error) bool {
+	return hasTag(err, "cant_transfer_ownership") || hasTag(err, "insufficient_quota")
+}
+
+// IsLockConflict returns true if err reports that a file is locked by
+// another user, either from a write such as an upload, or as the
+// *LockFileError of a lock batch entry.
+func IsLockConflict(err error) bool {
+	if e, ok := err.(*LockFileError); ok {
+		return e.Tag == "lock_conflict"
+	}
+	return hasSegment(err, "lock_conflict")
+}
+
+// DecodeError is returned when a successful response body is empty, or is
+// not the expected JSON, such as a truncated body or an HTML page from a
+// proxy.
+type DecodeError struct {
+	StatusCode  int
+	ContentType string
+
+	// Body is the start of the response body, up to 256 bytes.
+	Body string
+
+	Err error
+}
+
+// Error string.
+func (e *DecodeError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("dropbox: decoding %d response: empty body", e.StatusCode)
+	}
+	return fmt.Sprintf("dropbox: decoding %d response: %s: %q", e.StatusCode, e.Err, e.Body)
+}
+
+// Unwrap returns the JSON error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// asError returns the *Error of err, which may be wrapped such as by a
+// *PathConflictError.
+func asError(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// hasSegment returns true if err is an *Error with the given segment anywhere
+// in its "/" delimited summary.
+func hasSegment(err error, segment string) bool {
+	e, ok := asError(err)
+	if !ok {
+		return false
+	}
+
+	for _, s := range strings.Split(e.Summary, "/") {
+		if s == segment {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasTag returns true if err is an *Error whose summary begins with the
+// given "/" delimited tag path, such as "path/not_found".
+func hasTag(err error, tag string) bool {
+	e, ok := asError(err)
+	if !ok {
+		return false
+	}
+
+	tags := strings.Split(e.Summary, "/")
+	for i, t := range strings.Split(tag, "/") {
+		if i >= len(tags) || tags[i] != t {
+			return false
+		}
+	}
+
+	return true
+}
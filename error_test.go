@@ -0,0 +1,82 @@
+package dropbox
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_helpers(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in GetMetadataInput
+		assert.NoError(t, decodeArg(r, &in))
+
+		switch in.Path {
+		case "/missing":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(409)
+			w.Write([]byte(`{"error_summary": "path/not_found/..", "error": {".tag": "path", "path": {".tag": "not_found"}}}`))
+		case "/limited":
+			writeError(w, 429, "too_many_requests/..")
+		case "/busy":
+			writeError(w, 409, "path/too_many_write_operations/")
+		case "/conflict":
+			writeError(w, 409, "to/conflict/folder/")
+		}
+	})
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/missing"})
+	e, ok := err.(*Error)
+	assert.True(t, ok)
+	assert.Equal(t, 409, e.StatusCode)
+	assert.Equal(t, "path", e.Tag())
+	assert.True(t, IsNotFound(err))
+	assert.False(t, IsConflict(err))
+	assert.False(t, IsRateLimited(err))
+
+	_, err = c.Files.GetMetadata(&GetMetadataInput{Path: "/limited"})
+	assert.True(t, IsRateLimited(err))
+	assert.Equal(t, "too_many_requests", err.(*Error).Tag())
+
+	_, err = c.Files.GetMetadata(&GetMetadataInput{Path: "/busy"})
+	assert.True(t, IsRateLimited(err))
+
+	_, err = c.Files.GetMetadata(&GetMetadataInput{Path: "/conflict"})
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsNotFound(err))
+
+	assert.False(t, IsNotFound(errors.New("path/not_found")))
+}
+
+func TestError_Body(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/get_metadata":
+			writeError(w, 409, "path/not_found/..")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(502)
+			w.Write([]byte("<html>Bad Gateway</html>"))
+		}
+	})
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a"})
+	e := err.(*Error)
+	assert.Equal(t, "path/not_found/..", e.Summary)
+	assert.JSONEq(t, `{"error_summary": "path/not_found/.."}`, string(e.Body))
+
+	_, err = c.Files.Download(&DownloadInput{Path: "/a"})
+	e = err.(*Error)
+	assert.Equal(t, 502, e.StatusCode)
+	assert.Equal(t, "<html>Bad Gateway</html>", e.Summary)
+	assert.Equal(t, []byte("<html>Bad Gateway</html>"), e.Body)
+}
+
+func TestIsCursorReset(t *testing.T) {
+	assert.True(t, IsCursorReset(ErrCursorReset))
+	assert.True(t, IsCursorReset(&Error{Summary: "reset/.."}))
+	assert.False(t, IsCursorReset(&Error{Summary: "path/not_found/.."}))
+	assert.False(t, IsCursorReset(nil))
+}
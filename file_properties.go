@@ -0,0 +1,142 @@
+package dropbox
+
+import (
+	"encoding/json"
+)
+
+// FileProperties client for custom property templates, used to tag files
+// with PropertyGroups.
+type FileProperties struct {
+	*Client
+}
+
+// NewFileProperties client.
+func NewFileProperties(config *Config) *FileProperties {
+	return &FileProperties{
+		Client: &Client{
+			Config: config,
+		},
+	}
+}
+
+// PropertyType is the type of a property field's value.
+type PropertyType string
+
+// Property types supported.
+const (
+	PropertyTypeString PropertyType = "string"
+)
+
+// MarshalJSON encodes the type as a union.
+func (t PropertyType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{".tag": string(t)})
+}
+
+// UnmarshalJSON decodes the type.
+func (t *PropertyType) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Tag string `json:".tag"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*t = PropertyType(v.Tag)
+	return nil
+}
+
+// TemplateFilter selects the property groups returned with metadata: those of
+// the TemplateIDs, or all groups when it is empty.
+type TemplateFilter struct {
+	TemplateIDs []string
+}
+
+// MarshalJSON encodes the filter as a union.
+func (f *TemplateFilter) MarshalJSON() ([]byte, error) {
+	if len(f.TemplateIDs) == 0 {
+		return json.Marshal(map[string]string{".tag": "filter_none"})
+	}
+
+	return json.Marshal(map[string]interface{}{
+		".tag":        "filter_some",
+		"filter_some": f.TemplateIDs,
+	})
+}
+
+// PropertyFieldTemplate describes a field of a property template.
+type PropertyFieldTemplate struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Type        PropertyType `json:"type"`
+}
+
+// PropertyTemplate describes the fields of a property group.
+type PropertyTemplate struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Fields      []*PropertyFieldTemplate `json:"fields"`
+}
+
+// AddTemplateOutput request output.
+type AddTemplateOutput struct {
+	TemplateID string `json:"template_id"`
+}
+
+// AddTemplate adds a property template for the user.
+func (c *FileProperties) AddTemplate(in *PropertyTemplate) (out *AddTemplateOutput, err error) {
+	body, err := c.call("/file_properties/templates/add_for_user", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// GetTemplateInput request input.
+type GetTemplateInput struct {
+	TemplateID string `json:"template_id"`
+}
+
+// GetTemplate returns a property template of the user.
+func (c *FileProperties) GetTemplate(in *GetTemplateInput) (out *PropertyTemplate, err error) {
+	body, err := c.call("/file_properties/templates/get_for_user", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListTemplatesOutput request output.
+type ListTemplatesOutput struct {
+	TemplateIDs []string `json:"template_ids"`
+}
+
+// ListTemplates returns the IDs of the user's property templates.
+func (c *FileProperties) ListTemplates() (out *ListTemplatesOutput, err error) {
+	body, err := c.call("/file_properties/templates/list_for_user", nil)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// AddPropertiesInput request input.
+type AddPropertiesInput struct {
+	Path           string           `json:"path"`
+	PropertyGroups []*PropertyGroup `json:"property_groups"`
+}
+
+// AddProperties attaches property groups to a file or folder, which must not
+// already have a group of the same template.
+func (c *FileProperties) AddProperties(in *AddPropertiesInput) error {
+	return discard(c.call("/file_properties/properties/add", in))
+}
@@ -0,0 +1,108 @@
+package dropbox
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileProperties_AddTemplate(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/file_properties/templates/add_for_user", r.URL.Path)
+
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"name": "Security",
+			"description": "These properties describe how confidential this file or folder is.",
+			"fields": [{"name": "Security Policy", "description": "This is the security policy of the file or folder described.", "type": {".tag": "string"}}]
+		}`, string(b))
+
+		writeJSON(w, 200, map[string]string{"template_id": "ptid:1a5n2i6d3OYEAAAAAAAAAYa"})
+	})
+
+	out, err := c.FileProperties.AddTemplate(&PropertyTemplate{
+		Name:        "Security",
+		Description: "These properties describe how confidential this file or folder is.",
+		Fields: []*PropertyFieldTemplate{{
+			Name:        "Security Policy",
+			Description: "This is the security policy of the file or folder described.",
+			Type:        PropertyTypeString,
+		}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ptid:1a5n2i6d3OYEAAAAAAAAAYa", out.TemplateID)
+}
+
+func TestFileProperties_GetTemplate(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in GetTemplateInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "ptid:1", in.TemplateID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "Security", "description": "", "fields": [{"name": "Security Policy", "description": "", "type": {".tag": "string"}}]}`))
+	})
+
+	out, err := c.FileProperties.GetTemplate(&GetTemplateInput{TemplateID: "ptid:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Security", out.Name)
+	assert.Equal(t, PropertyTypeString, out.Fields[0].Type)
+}
+
+func TestFileProperties_AddProperties(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/file_properties/properties/add", r.URL.Path)
+
+		var in AddPropertiesInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/a.txt", in.Path)
+		assert.Equal(t, "Confidential", in.PropertyGroups[0].Fields[0].Value)
+
+		writeJSON(w, 200, nil)
+	})
+
+	err := c.FileProperties.AddProperties(&AddPropertiesInput{
+		Path: "/a.txt",
+		PropertyGroups: []*PropertyGroup{{
+			TemplateID: "ptid:1",
+			Fields:     []PropertyField{{Name: "Security Policy", Value: "Confidential"}},
+		}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestFiles_GetMetadata_includePropertyGroups(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/2/files/get_metadata":
+			assert.Contains(t, string(b), `"include_property_groups":{".tag":"filter_some","filter_some":["ptid:1"]}`)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{
+				".tag": "file",
+				"path_lower": "/a.txt",
+				"property_groups": [{"template_id": "ptid:1", "fields": [{"name": "Security Policy", "value": "Confidential"}]}]
+			}`))
+		case "/2/files/list_folder":
+			assert.Contains(t, string(b), `"include_property_groups":{".tag":"filter_none"}`)
+			writeJSON(w, 200, map[string]interface{}{"entries": []interface{}{}})
+		}
+	})
+
+	out, err := c.Files.GetMetadata(&GetMetadataInput{
+		Path:                  "/a.txt",
+		IncludePropertyGroups: &TemplateFilter{TemplateIDs: []string{"ptid:1"}},
+	})
+	assert.NoError(t, err)
+
+	file := out.Entry().(*FileMetadata)
+	assert.Equal(t, "Confidential", file.PropertyGroups[0].Fields[0].Value)
+
+	_, err = c.Files.ListFolder(&ListFolderInput{Path: "", IncludePropertyGroups: &TemplateFilter{}})
+	assert.NoError(t, err)
+}
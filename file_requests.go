@@ -0,0 +1,174 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FileRequests client for collecting files from others with file requests.
+type FileRequests struct {
+	*Client
+}
+
+// NewFileRequests client.
+func NewFileRequests(config *Config) *FileRequests {
+	return &FileRequests{
+		Client: &Client{
+			Config: config,
+		},
+	}
+}
+
+// GracePeriod is how long after its deadline a file request accepts uploads.
+type GracePeriod string
+
+// Grace periods supported.
+const (
+	GracePeriodOneDay     GracePeriod = "one_day"
+	GracePeriodTwoDays                = "two_days"
+	GracePeriodSevenDays              = "seven_days"
+	GracePeriodThirtyDays             = "thirty_days"
+	GracePeriodAlways                 = "always"
+)
+
+// FileRequestDeadline is the time after which a file request no longer
+// accepts uploads, unless allowed late by a grace period.
+type FileRequestDeadline struct {
+	Deadline         time.Time
+	AllowLateUploads GracePeriod
+}
+
+// MarshalJSON encodes the deadline, which Dropbox requires in UTC without
+// fractional seconds.
+func (d *FileRequestDeadline) MarshalJSON() ([]byte, error) {
+	v := map[string]interface{}{
+		"deadline": FormatTime(d.Deadline),
+	}
+
+	if d.AllowLateUploads != "" {
+		v["allow_late_uploads"] = map[string]GracePeriod{".tag": d.AllowLateUploads}
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes the deadline.
+func (d *FileRequestDeadline) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Deadline         time.Time `json:"deadline"`
+		AllowLateUploads *struct {
+			Tag GracePeriod `json:".tag"`
+		} `json:"allow_late_uploads"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	d.Deadline = v.Deadline
+	d.AllowLateUploads = ""
+	if v.AllowLateUploads != nil {
+		d.AllowLateUploads = v.AllowLateUploads.Tag
+	}
+
+	return nil
+}
+
+// FileRequest collects uploads from others into its Destination folder.
+type FileRequest struct {
+	ID          string               `json:"id"`
+	URL         string               `json:"url"`
+	Title       string               `json:"title"`
+	Created     time.Time            `json:"created"`
+	IsOpen      bool                 `json:"is_open"`
+	FileCount   int64                `json:"file_count"`
+	Destination string               `json:"destination,omitempty"`
+	Deadline    *FileRequestDeadline `json:"deadline,omitempty"`
+	Description string               `json:"description,omitempty"`
+}
+
+// CreateFileRequestInput request input. Open defaults to false, so set it
+// for the request to accept uploads immediately.
+type CreateFileRequestInput struct {
+	Title       string               `json:"title"`
+	Destination string               `json:"destination"`
+	Deadline    *FileRequestDeadline `json:"deadline,omitempty"`
+	Open        bool                 `json:"open"`
+	Description string               `json:"description,omitempty"`
+}
+
+// Create a file request.
+func (c *FileRequests) Create(in *CreateFileRequestInput) (out *FileRequest, err error) {
+	body, err := c.call("/file_requests/create", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// GetFileRequestInput request input.
+type GetFileRequestInput struct {
+	ID string `json:"id"`
+}
+
+// Get a file request.
+func (c *FileRequests) Get(in *GetFileRequestInput) (out *FileRequest, err error) {
+	body, err := c.call("/file_requests/get", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFileRequestsInput request input.
+type ListFileRequestsInput struct {
+	Limit uint64 `json:"limit,omitempty"`
+}
+
+// ListFileRequestsOutput request output. When HasMore is true the next page
+// is returned by ListContinue with the Cursor.
+type ListFileRequestsOutput struct {
+	FileRequests []*FileRequest `json:"file_requests"`
+	Cursor       string         `json:"cursor"`
+	HasMore      bool           `json:"has_more"`
+}
+
+// List the file requests of the user.
+func (c *FileRequests) List(in *ListFileRequestsInput) (out *ListFileRequestsOutput, err error) {
+	body, err := c.call("/file_requests/list_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFileRequestsContinueInput request input.
+type ListFileRequestsContinueInput struct {
+	Cursor string `json:"cursor"`
+}
+
+// ListContinue returns the next page of file requests, returning
+// ErrEmptyCursor when there is no cursor.
+func (c *FileRequests) ListContinue(in *ListFileRequestsContinueInput) (out *ListFileRequestsOutput, err error) {
+	if in.Cursor == "" {
+		return nil, ErrEmptyCursor
+	}
+
+	body, err := c.call("/file_requests/list/continue", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
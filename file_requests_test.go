@@ -0,0 +1,81 @@
+package dropbox
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRequests_Create(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/file_requests/create", r.URL.Path)
+
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"title": "Photos",
+			"destination": "/Photos/Wedding",
+			"deadline": {"deadline": "2026-06-01T12:00:00Z", "allow_late_uploads": {".tag": "seven_days"}},
+			"open": true
+		}`, string(b))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "oaCAVmEyrqYnkZX9955Y",
+			"url": "https://www.dropbox.com/request/oaCAVmEyrqYnkZX9955Y",
+			"title": "Photos",
+			"created": "2026-05-01T12:00:00Z",
+			"is_open": true,
+			"file_count": 0,
+			"destination": "/Photos/Wedding",
+			"deadline": {"deadline": "2026-06-01T12:00:00Z", "allow_late_uploads": {".tag": "seven_days"}}
+		}`))
+	})
+
+	deadline := time.Date(2026, 6, 1, 14, 0, 0, 500, time.FixedZone("CEST", 2*60*60))
+	out, err := c.FileRequests.Create(&CreateFileRequestInput{
+		Title:       "Photos",
+		Destination: "/Photos/Wedding",
+		Deadline:    &FileRequestDeadline{deadline, GracePeriodSevenDays},
+		Open:        true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.dropbox.com/request/oaCAVmEyrqYnkZX9955Y", out.URL)
+	assert.True(t, out.IsOpen)
+	assert.True(t, deadline.Truncate(time.Second).Equal(out.Deadline.Deadline))
+	assert.Equal(t, GracePeriod(GracePeriodSevenDays), out.Deadline.AllowLateUploads)
+}
+
+func TestFileRequests_List(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/file_requests/list_v2":
+			writeJSON(w, 200, map[string]interface{}{
+				"file_requests": []map[string]interface{}{{"id": "a", "title": "A"}},
+				"cursor":        "c1",
+				"has_more":      true,
+			})
+		case "/2/file_requests/list/continue":
+			var in ListFileRequestsContinueInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "c1", in.Cursor)
+			writeJSON(w, 200, map[string]interface{}{
+				"file_requests": []map[string]interface{}{{"id": "b", "title": "B"}},
+				"has_more":      false,
+			})
+		}
+	})
+
+	out, err := c.FileRequests.List(&ListFileRequestsInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", out.FileRequests[0].ID)
+	assert.Nil(t, out.FileRequests[0].Deadline)
+
+	out, err = c.FileRequests.ListContinue(&ListFileRequestsContinueInput{out.Cursor})
+	assert.NoError(t, err)
+	assert.Equal(t, "B", out.FileRequests[0].Title)
+	assert.False(t, out.HasMore)
+}
@@ -1,11 +1,22 @@
 package dropbox
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +34,11 @@ func NewFiles(config *Config) *Files {
 	}
 }
 
+// WithContext returns a copy of the client whose requests use ctx.
+func (c *Files) WithContext(ctx context.Context) *Files {
+	return c.Client.WithContext(ctx).Files
+}
+
 // WriteMode determines what to do if the file already exists.
 type WriteMode string
 
@@ -32,6 +48,56 @@ const (
 	WriteModeOverwrite           = "overwrite"
 )
 
+// writeModeUpdatePrefix prefixes the rev of a WriteModeUpdate.
+const writeModeUpdatePrefix = "update:"
+
+// WriteModeUpdate overwrites the file only if its current rev is rev. If it
+// has changed the write conflicts, and Upload returns a *RevConflictError.
+func WriteModeUpdate(rev string) WriteMode {
+	return WriteMode(writeModeUpdatePrefix + rev)
+}
+
+// Rev returns the rev of a WriteModeUpdate, or an empty string.
+func (m WriteMode) Rev() string {
+	if strings.HasPrefix(string(m), writeModeUpdatePrefix) {
+		return strings.TrimPrefix(string(m), writeModeUpdatePrefix)
+	}
+	return ""
+}
+
+// MarshalJSON encodes the write mode union, where update mode is an object.
+func (m WriteMode) MarshalJSON() ([]byte, error) {
+	if rev := m.Rev(); rev != "" {
+		return json.Marshal(map[string]string{".tag": "update", "update": rev})
+	}
+	return json.Marshal(string(m))
+}
+
+// UnmarshalJSON decodes the write mode union.
+func (m *WriteMode) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Tag    string `json:".tag"`
+		Update string `json:"update"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*m = WriteMode(s)
+		return nil
+	}
+
+	if v.Tag == "update" {
+		*m = WriteModeUpdate(v.Update)
+		return nil
+	}
+
+	*m = WriteMode(v.Tag)
+	return nil
+}
+
 // Dimensions specifies the dimensions of a photo or video.
 type Dimensions struct {
 	Width  uint64 `json:"width"`
@@ -66,18 +132,86 @@ type MediaMetadata struct {
 }
 
 // MediaInfo provides additional information for a photo or video file.
+// Metadata is nil while Dropbox is still processing the file.
 type MediaInfo struct {
 	Pending  bool           `json:"pending"`
 	Metadata *MediaMetadata `json:"metadata,omitempty"`
 }
 
-// FileSharingInfo for a file which is contained in a shared folder.
+// IsPending returns whether the media metadata is not yet available.
+func (m *MediaInfo) IsPending() bool {
+	return m.Pending
+}
+
+// UnmarshalJSON decodes the media_info union, which is either
+// {".tag": "pending"} or the photo or video metadata. Missing fields and
+// unparsable times are left empty rather than failing the whole response.
+func (m *MediaInfo) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Tag      string `json:".tag"`
+		Metadata *struct {
+			Tag        string          `json:".tag"`
+			Dimensions *Dimensions     `json:"dimensions"`
+			Location   *GPSCoordinates `json:"location"`
+			TimeTaken  string          `json:"time_taken"`
+			Duration   uint64          `json:"duration"`
+		} `json:"metadata"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*m = MediaInfo{Pending: v.Tag == "pending"}
+
+	meta := v.Metadata
+	if v.Tag != "metadata" || meta == nil {
+		return nil
+	}
+
+	taken, _ := time.Parse(time.RFC3339, meta.TimeTaken)
+
+	switch meta.Tag {
+	case "photo":
+		m.Metadata = &MediaMetadata{Photo: &PhotoMetadata{
+			Dimensions: meta.Dimensions,
+			Location:   meta.Location,
+			TimeTaken:  taken,
+		}}
+	case "video":
+		m.Metadata = &MediaMetadata{Video: &VideoMetadata{
+			Dimensions: meta.Dimensions,
+			Location:   meta.Location,
+			TimeTaken:  taken,
+			Duration:   meta.Duration,
+		}}
+	}
+
+	return nil
+}
+
+// FileSharingInfo for a file which is contained in a shared folder. For a
+// folder which is itself shared SharedFolderID is also set.
 type FileSharingInfo struct {
 	ReadOnly             bool   `json:"read_only"`
 	ParentSharedFolderID string `json:"parent_shared_folder_id"`
+	SharedFolderID       string `json:"shared_folder_id,omitempty"`
 	ModifiedBy           string `json:"modified_by,omitempty"`
 }
 
+// PropertyField is a name and value of a property group.
+type PropertyField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PropertyGroup is a set of custom properties attached to a file, described
+// by the property template TemplateID.
+type PropertyGroup struct {
+	TemplateID string          `json:"template_id"`
+	Fields     []PropertyField `json:"fields"`
+}
+
 // Metadata for a file or folder.
 type Metadata struct {
 	Tag            string           `json:".tag"`
@@ -92,12 +226,41 @@ type Metadata struct {
 	MediaInfo      *MediaInfo       `json:"media_info,omitempty"`
 	SharingInfo    *FileSharingInfo `json:"sharing_info,omitempty"`
 	ContentHash    string           `json:"content_hash,omitempty"`
+	PropertyGroups []*PropertyGroup `json:"property_groups,omitempty"`
+
+	// IsDownloadable is false for files which cannot be downloaded directly,
+	// such as Google Docs, which must be exported instead.
+	IsDownloadable bool `json:"is_downloadable,omitempty"`
+
+	// ExportInfo is set for files which must be exported.
+	ExportInfo *ExportInfo `json:"export_info,omitempty"`
+
+	HasExplicitSharedMembers bool `json:"has_explicit_shared_members,omitempty"`
+
+	// FileLockInfo is set for files which are locked.
+	FileLockInfo *FileLockInfo `json:"file_lock_info,omitempty"`
+}
+
+// FileLockInfo describes the lock on a file.
+type FileLockInfo struct {
+	IsLockholder        bool      `json:"is_lockholder"`
+	LockholderName      string    `json:"lockholder_name,omitempty"`
+	LockholderAccountID string    `json:"lockholder_account_id,omitempty"`
+	Created             time.Time `json:"created,omitempty"`
 }
 
 // GetMetadataInput request input.
 type GetMetadataInput struct {
-	Path             string `json:"path"`
-	IncludeMediaInfo bool   `json:"include_media_info"`
+	Path                            string `json:"path"`
+	IncludeMediaInfo                bool   `json:"include_media_info"`
+	IncludeHasExplicitSharedMembers bool   `json:"include_has_explicit_shared_members"`
+
+	// IncludeDeleted returns DeletedMetadata for a file or folder which has
+	// been deleted, instead of a path/not_found error.
+	IncludeDeleted bool `json:"include_deleted,omitempty"`
+
+	// IncludePropertyGroups returns the PropertyGroups matching the filter.
+	IncludePropertyGroups *TemplateFilter `json:"include_property_groups,omitempty"`
 }
 
 // GetMetadataOutput request output.
@@ -107,16 +270,42 @@ type GetMetadataOutput struct {
 
 // GetMetadata returns the metadata for a file or folder.
 func (c *Files) GetMetadata(in *GetMetadataInput) (out *GetMetadataOutput, err error) {
+	in.Path = normalizePath(in.Path)
+
 	body, err := c.call("/files/get_metadata", in)
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
+// IsShared returns whether the file or folder at path is shared, along with
+// the ID of the shared folder containing it. For a shared folder which is not
+// itself nested in another, its own shared folder ID is returned.
+func (c *Files) IsShared(path string) (bool, string, error) {
+	out, err := c.GetMetadata(&GetMetadataInput{
+		Path:                            path,
+		IncludeHasExplicitSharedMembers: true,
+	})
+	if err != nil {
+		return false, "", err
+	}
+
+	info := out.SharingInfo
+	if info == nil {
+		return out.HasExplicitSharedMembers, "", nil
+	}
+
+	if info.ParentSharedFolderID != "" {
+		return true, info.ParentSharedFolderID, nil
+	}
+
+	return info.SharedFolderID != "" || out.HasExplicitSharedMembers, info.SharedFolderID, nil
+}
+
 // CreateFolderInput request input.
 type CreateFolderInput struct {
 	Path string `json:"path"`
@@ -137,273 +326,3043 @@ func (c *Files) CreateFolder(in *CreateFolderInput) (out *CreateFolderOutput, er
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
-// DeleteInput request input.
-type DeleteInput struct {
-	Path string `json:"path"`
+// CreateFolderIfNotExists creates a folder as CreateFolder does, returning
+// the existing folder when there is already one at the path. A conflict with
+// a file is still an error.
+func (c *Files) CreateFolderIfNotExists(in *CreateFolderInput) (*CreateFolderOutput, error) {
+	out, err := c.CreateFolder(in)
+	if !hasTag(err, "path/conflict/folder") {
+		return out, err
+	}
+
+	existing, err := c.GetMetadata(&GetMetadataInput{Path: in.Path})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateFolderOutput{
+		Name:      existing.Name,
+		PathLower: existing.PathLower,
+		ID:        existing.ID,
+	}, nil
 }
 
-// DeleteOutput request output.
-type DeleteOutput struct {
-	Metadata
+// MkdirAll creates the folder at path along with any missing parents,
+// succeeding if it already exists.
+func (c *Files) MkdirAll(path string) error {
+	if normalizePath(path) == "" {
+		return nil
+	}
+
+	_, err := c.CreateFolder(&CreateFolderInput{Path: path})
+	if hasTag(err, "path/conflict/folder") {
+		return nil
+	}
+
+	return err
 }
 
-// Delete a file or folder and its contents.
-func (c *Files) Delete(in *DeleteInput) (out *DeleteOutput, err error) {
-	body, err := c.call("/files/delete", in)
-	if err != nil {
-		return
+// WriteError describes why a write to a path failed. When Tag is "conflict"
+// the Conflict tag is "file", "folder" or "file_ancestor".
+type WriteError struct {
+	Tag      string `json:".tag"`
+	Conflict struct {
+		Tag string `json:".tag"`
+	} `json:"conflict"`
+}
+
+// tags returns the tag path of the error, such as "conflict/file".
+func (e *WriteError) tags() []string {
+	if e.Tag == "conflict" && e.Conflict.Tag != "" {
+		return []string{e.Tag, e.Conflict.Tag}
 	}
-	defer body.Close()
+	return []string{e.Tag}
+}
 
-	err = json.NewDecoder(body).Decode(&out)
-	return
+// CreateFolderError describes why a folder could not be created.
+type CreateFolderError struct {
+	Tag  string     `json:".tag"`
+	Path WriteError `json:"path"`
 }
 
-// PermanentlyDeleteInput request input.
-type PermanentlyDeleteInput struct {
-	Path string `json:"path"`
+// CreateFolderBatchInput request input.
+type CreateFolderBatchInput struct {
+	Paths      []string `json:"paths"`
+	AutoRename bool     `json:"autorename"`
+	ForceAsync bool     `json:"force_async"`
 }
 
-// PermanentlyDelete a file or folder and its contents.
-func (c *Files) PermanentlyDelete(in *PermanentlyDeleteInput) (err error) {
-	body, err := c.call("/files/delete", in)
+// CreateFolderBatchResultEntry is the result of creating one folder, where
+// Tag is "success" or "failure".
+type CreateFolderBatchResultEntry struct {
+	Tag      string             `json:".tag"`
+	Metadata *Metadata          `json:"metadata,omitempty"`
+	Failure  *CreateFolderError `json:"failure,omitempty"`
+}
+
+// CreateFolderBatchOutput request output. Tag is "complete" with the Entries
+// in the order requested, or "async_job_id" when the job must be polled with
+// CreateFolderBatchCheckJobStatus.
+type CreateFolderBatchOutput struct {
+	LaunchResult
+	Entries []*CreateFolderBatchResultEntry `json:"entries,omitempty"`
+}
+
+// CreateFolderBatch creates up to 10,000 folders.
+func (c *Files) CreateFolderBatch(in *CreateFolderBatchInput) (out *CreateFolderBatchOutput, err error) {
+	body, err := c.call("/files/create_folder_batch", in)
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
+	err = decode(body, &out)
 	return
 }
 
-// CopyInput request input.
-type CopyInput struct {
-	FromPath string `json:"from_path"`
-	ToPath   string `json:"to_path"`
-}
+// CreateFolderBatchCheckJobStatusInput request input.
+type CreateFolderBatchCheckJobStatusInput = AsyncJobID
 
-// CopyOutput request output.
-type CopyOutput struct {
-	Metadata
+// CreateFolderBatchCheckJobStatusOutput request output. Tag is "in_progress",
+// "complete" with the Entries, or "failed".
+type CreateFolderBatchCheckJobStatusOutput struct {
+	Tag     string                          `json:".tag"`
+	Entries []*CreateFolderBatchResultEntry `json:"entries,omitempty"`
 }
 
-// Copy a file or folder to a different location.
-func (c *Files) Copy(in *CopyInput) (out *CopyOutput, err error) {
-	body, err := c.call("/files/copy", in)
+// CreateFolderBatchCheckJobStatus returns the status of an asynchronous CreateFolderBatch job.
+func (c *Files) CreateFolderBatchCheckJobStatus(in *CreateFolderBatchCheckJobStatusInput) (out *CreateFolderBatchCheckJobStatusOutput, err error) {
+	body, err := c.call("/files/create_folder_batch/check_job_status", in)
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
-// MoveInput request input.
-type MoveInput struct {
-	FromPath string `json:"from_path"`
-	ToPath   string `json:"to_path"`
+// CreateFolderBatchWait creates a batch of folders as CreateFolderBatch does,
+// polling with opts until an asynchronous job completes or the client's
+// context is done, and returns the result for each path in the same order.
+func (c *Files) CreateFolderBatchWait(in *CreateFolderBatchInput, opts *PollOptions) ([]*CreateFolderBatchResultEntry, error) {
+	out, err := c.CreateFolderBatch(in)
+	if err != nil {
+		return nil, err
+	}
+
+	switch out.Tag {
+	case "complete":
+		return out.Entries, nil
+	case "async_job_id":
+		return c.waitCreateFolderBatch(c.context(), out.Job(), opts)
+	default:
+		return nil, fmt.Errorf("dropbox: creating folders: %s", out.Tag)
+	}
 }
 
-// MoveOutput request output.
-type MoveOutput struct {
+// DeleteInput request input.
+type DeleteInput struct {
+	Path string `json:"path"`
+
+	// ParentRev deletes the file only if its current rev is ParentRev. It is
+	// not supported when deleting a folder.
+	ParentRev string `json:"parent_rev,omitempty"`
+}
+
+// DeleteOutput request output.
+type DeleteOutput struct {
 	Metadata
 }
 
-// Move a file or folder to a different location.
-func (c *Files) Move(in *MoveInput) (out *MoveOutput, err error) {
-	body, err := c.call("/files/move", in)
+// Delete a file or folder and its contents. When ParentRev is set and the
+// file is no longer at that rev, a *RevConflictError is returned.
+func (c *Files) Delete(in *DeleteInput) (out *DeleteOutput, err error) {
+	body, err := c.call("/files/delete_v2", in)
+	if in.ParentRev != "" && hasTag(err, "path_write/conflict") {
+		return nil, c.revConflict(in.Path, in.ParentRev)
+	}
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	var res relocationResult
+	if err = decode(body, &res); err != nil {
+		return
+	}
+
+	out = &DeleteOutput{res.Metadata}
 	return
 }
 
-// RestoreInput request input.
-type RestoreInput struct {
-	Path string `json:"path"`
-	Rev  string `json:"rev"`
+// revConflict returns a *RevConflictError for path, fetching its current rev.
+func (c *Files) revConflict(path, expectRev string) error {
+	out, err := c.GetMetadata(&GetMetadataInput{Path: path})
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+
+	conflict := &RevConflictError{Path: path, ExpectRev: expectRev}
+	if out != nil && out.Tag == "file" {
+		conflict.Rev = out.Rev
+	}
+	return conflict
 }
 
-// RestoreOutput request output.
-type RestoreOutput struct {
-	Metadata
+// PermanentlyDeleteInput request input.
+type PermanentlyDeleteInput struct {
+	Path string `json:"path"`
 }
 
-// Restore a file to a specific revision.
-func (c *Files) Restore(in *RestoreInput) (out *RestoreOutput, err error) {
-	body, err := c.call("/files/restore", in)
+// PermanentlyDelete a file or folder and its contents.
+func (c *Files) PermanentlyDelete(in *PermanentlyDeleteInput) (err error) {
+	body, err := c.call("/files/delete", in)
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
 	return
 }
 
-// ListFolderInput request input.
-type ListFolderInput struct {
-	Path             string `json:"path"`
-	Recursive        bool   `json:"recursive"`
-	IncludeMediaInfo bool   `json:"include_media_info"`
-	IncludeDeleted   bool   `json:"include_deleted"`
+// deleteBatchLimit is the maximum number of entries in a DeleteBatch.
+const deleteBatchLimit = 1000
+
+// DeleteError describes why an entry of a batch delete failed. When Tag is
+// "path_lookup" the PathLookup tag is "not_found", "malformed_path" etc.
+type DeleteError struct {
+	Tag        string `json:".tag"`
+	PathLookup *struct {
+		Tag string `json:".tag"`
+	} `json:"path_lookup,omitempty"`
+	PathWrite *WriteError `json:"path_write,omitempty"`
 }
 
-// ListFolderOutput request output.
-type ListFolderOutput struct {
-	Cursor  string `json:"cursor"`
-	HasMore bool   `json:"has_more"`
-	Entries []*Metadata
+// DeleteBatchResultEntry is the result of deleting one entry, where Tag is
+// "success" or "failure".
+type DeleteBatchResultEntry struct {
+	Tag      string       `json:".tag"`
+	Metadata *Metadata    `json:"metadata,omitempty"`
+	Failure  *DeleteError `json:"failure,omitempty"`
+
+	// Err is set by DeleteMany, with a Tag of "failure", when the batch of
+	// the entry failed as a whole.
+	Err error `json:"-"`
 }
 
-// ListFolder returns the metadata for a file or folder.
-func (c *Files) ListFolder(in *ListFolderInput) (out *ListFolderOutput, err error) {
-	in.Path = normalizePath(in.Path)
+// DeleteBatchInput request input.
+type DeleteBatchInput struct {
+	Entries []*DeleteInput `json:"entries"`
+}
 
-	body, err := c.call("/files/list_folder", in)
+// DeleteBatchOutput request output. Tag is "complete" with the Entries in the
+// order requested, or "async_job_id" when the job must be polled with
+// DeleteBatchCheckJobStatus.
+type DeleteBatchOutput struct {
+	LaunchResult
+	Entries []*DeleteBatchResultEntry `json:"entries,omitempty"`
+}
+
+// DeleteBatch deletes up to 1000 files or folders at once. See
+// UploadDirOptions.Mute on notifications.
+func (c *Files) DeleteBatch(in *DeleteBatchInput) (out *DeleteBatchOutput, err error) {
+	body, err := c.call("/files/delete_batch", in)
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
-// ListFolderContinueInput request input.
-type ListFolderContinueInput struct {
-	Cursor string `json:"cursor"`
+// DeleteBatchCheckJobStatusInput request input.
+type DeleteBatchCheckJobStatusInput = AsyncJobID
+
+// DeleteBatchCheckJobStatusOutput request output. Tag is "in_progress",
+// "complete" with the Entries, or "failed".
+type DeleteBatchCheckJobStatusOutput struct {
+	Tag     string                    `json:".tag"`
+	Entries []*DeleteBatchResultEntry `json:"entries,omitempty"`
+	Failed  *struct {
+		Tag string `json:".tag"`
+	} `json:"failed,omitempty"`
 }
 
-// ListFolderContinue pagenates using the cursor from ListFolder.
-func (c *Files) ListFolderContinue(in *ListFolderContinueInput) (out *ListFolderOutput, err error) {
-	body, err := c.call("/files/list_folder/continue", in)
+// DeleteBatchCheckJobStatus returns the status of an asynchronous DeleteBatch job.
+func (c *Files) DeleteBatchCheckJobStatus(in *DeleteBatchCheckJobStatusInput) (out *DeleteBatchCheckJobStatusOutput, err error) {
+	body, err := c.call("/files/delete_batch/check_job_status", in)
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
-// SearchMode determines how a search is performed.
-type SearchMode string
+// DeleteMany deletes paths in batches of up to 1000, up to parallelism
+// batches at a time, waiting for each to complete. The result for each path
+// is returned in the same order as paths, even when a batch fails, in which
+// case its entries have the batch's Err and the first such error is
+// returned. Polling for the batches to complete stops when the client's
+// context is done.
+func (c *Files) DeleteMany(paths []string, parallelism int) ([]*DeleteBatchResultEntry, error) {
+	n := (len(paths) + deleteBatchLimit - 1) / deleteBatchLimit
+	results := make([]*DeleteBatchResultEntry, len(paths))
+	errs := make([]error, n)
 
-// Supported search modes.
-const (
-	SearchModeFilename           SearchMode = "filename"
-	SearchModeFilenameAndContent            = "filename_and_content"
-	SearchModeDeletedFilename               = "deleted_filename"
-)
+	parallel(n, parallelism, func(i int) {
+		start := i * deleteBatchLimit
+		end := start + deleteBatchLimit
+		if end > len(paths) {
+			end = len(paths)
+		}
 
-// SearchMatchType represents the type of match made.
-type SearchMatchType string
+		in := &DeleteBatchInput{}
+		for _, p := range paths[start:end] {
+			in.Entries = append(in.Entries, &DeleteInput{Path: p})
+		}
 
-// Supported search match types.
-const (
-	SearchMatchFilename SearchMatchType = "filename"
-	SearchMatchContent                  = "content"
-	SearchMatchBoth                     = "both"
-)
+		entries, err := c.DeleteBatchWait(in, nil)
+		if err == nil && len(entries) != len(in.Entries) {
+			err = fmt.Errorf("dropbox: deleting files: %d results for %d paths", len(entries), len(in.Entries))
+		}
+		if err != nil {
+			errs[i] = err
+			for k := start; k < end; k++ {
+				results[k] = &DeleteBatchResultEntry{Tag: "failure", Err: err}
+			}
+			return
+		}
 
-// SearchMatch represents a matched file or folder.
-type SearchMatch struct {
-	MatchType struct {
-		Tag SearchMatchType `json:".tag"`
-	} `json:"match_type"`
-	Metadata *Metadata `json:"metadata"`
-}
+		copy(results[start:end], entries)
+	})
 
-// SearchInput request input.
-type SearchInput struct {
-	Path       string     `json:"path"`
-	Query      string     `json:"query"`
-	Start      uint64     `json:"start,omitempty"`
-	MaxResults uint64     `json:"max_results,omitempty"`
-	Mode       SearchMode `json:"mode"`
-}
+	for _, err := range errs {
+		if err != nil {
+			return results, err
+		}
+	}
 
-// SearchOutput request output.
-type SearchOutput struct {
-	Matches []*SearchMatch `json:"matches"`
-	More    bool           `json:"more"`
-	Start   uint64         `json:"start"`
+	return results, nil
 }
 
-// Search for files and folders.
-func (c *Files) Search(in *SearchInput) (out *SearchOutput, err error) {
-	in.Path = normalizePath(in.Path)
-
-	if in.Mode == "" {
-		in.Mode = SearchModeFilename
+// DeleteBatchWait deletes a batch as DeleteBatch does, polling with opts
+// until it completes or the client's context is done, and returns the result
+// for each entry in the same order.
+func (c *Files) DeleteBatchWait(in *DeleteBatchInput, opts *PollOptions) ([]*DeleteBatchResultEntry, error) {
+	out, err := c.DeleteBatch(in)
+	if err != nil {
+		return nil, err
 	}
 
-	body, err := c.call("/files/search", in)
-	if err != nil {
-		return
+	switch out.Tag {
+	case "complete":
+		return out.Entries, nil
+	case "async_job_id":
+	default:
+		return nil, fmt.Errorf("dropbox: deleting files: %s", out.Tag)
 	}
-	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
-	return
+	var entries []*DeleteBatchResultEntry
+	err = c.PollJob(func() (bool, error) {
+		status, err := c.DeleteBatchCheckJobStatus(out.Job())
+		if err != nil {
+			return false, err
+		}
+
+		switch status.Tag {
+		case "in_progress":
+			return false, nil
+		case "complete":
+			entries = status.Entries
+			return true, nil
+		case "failed":
+			if status.Failed != nil {
+				return false, fmt.Errorf("dropbox: deleting files: %s", status.Failed.Tag)
+			}
+			fallthrough
+		default:
+			return false, fmt.Errorf("dropbox: deleting files: %s", status.Tag)
+		}
+	}, opts)
+
+	return entries, err
 }
 
-// UploadInput request input.
-type UploadInput struct {
-	Path           string    `json:"path"`
-	Mode           WriteMode `json:"mode"`
-	AutoRename     bool      `json:"autorename"`
-	Mute           bool      `json:"mute"`
-	ClientModified string    `json:"client_modified,omitempty"`
-	Reader         io.Reader `json:"-"`
+// CopyInput request input.
+type CopyInput struct {
+	FromPath               string `json:"from_path"`
+	ToPath                 string `json:"to_path"`
+	AllowSharedFolder      bool   `json:"allow_shared_folder,omitempty"`
+	AutoRename             bool   `json:"autorename,omitempty"`
+	AllowOwnershipTransfer bool   `json:"allow_ownership_transfer,omitempty"`
 }
 
-// UploadOutput request output.
-type UploadOutput struct {
+// CopyOutput request output.
+type CopyOutput struct {
 	Metadata
 }
 
-// Upload a file smaller than 150MB.
-func (c *Files) Upload(in *UploadInput) (out *UploadOutput, err error) {
-	body, _, err := c.download("/files/upload", in, in.Reader)
+// relocationResult is the result of copy_v2, move_v2 and delete_v2.
+type relocationResult struct {
+	Metadata Metadata `json:"metadata"`
+}
+
+// Copy a file or folder to a different location.
+func (c *Files) Copy(in *CopyInput) (out *CopyOutput, err error) {
+	body, err := c.call("/files/copy_v2", in)
 	if err != nil {
 		return
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	var res relocationResult
+	if err = decode(body, &res); err != nil {
+		return
+	}
+
+	out = &CopyOutput{res.Metadata}
 	return
 }
 
-// DownloadInput request input.
-type DownloadInput struct {
-	Path string `json:"path"`
+// RelocationPath is the source and destination of a copy or move.
+type RelocationPath struct {
+	FromPath string `json:"from_path"`
+	ToPath   string `json:"to_path"`
 }
 
-// DownloadOutput request output.
-type DownloadOutput struct {
-	Body   io.ReadCloser
-	Length int64
+// RelocationBatchError describes why an entry of a batch copy or move failed.
+// Tag is "relocation_error" with the RelocationError, or another failure such
+// as "too_many_write_operations".
+type RelocationBatchError struct {
+	Tag             string           `json:".tag"`
+	RelocationError *RelocationError `json:"relocation_error,omitempty"`
 }
 
-// Download a file.
-func (c *Files) Download(in *DownloadInput) (out *DownloadOutput, err error) {
-	body, l, err := c.download("/files/download", in, nil)
-	if err != nil {
-		return
-	}
+// Error string, such as "dropbox: relocating: relocation_error/from_lookup/not_found".
+func (e *RelocationBatchError) Error() string {
+	tags := []string{e.Tag}
 
-	out = &DownloadOutput{body, l}
-	return
-}
+	if r := e.RelocationError; r != nil {
+		tags = append(tags, r.Tag)
+
+		switch {
+		case r.FromLookup != nil:
+			tags = append(tags, r.FromLookup.Tag)
+		case r.FromWrite != nil:
+			tags = append(tags, r.FromWrite.tags()...)
+		case r.To != nil:
+			tags = append(tags, r.To.tags()...)
+		}
+	}
+
+	return "dropbox: relocating: " + strings.Join(tags, "/")
+}
+
+// RelocationError describes why a copy or move failed. When Tag is
+// "from_lookup", "from_write" or "to" the corresponding field is set, other
+// failures include "cant_copy_shared_folder", "too_many_files" and
+// "insufficient_quota".
+type RelocationError struct {
+	Tag        string `json:".tag"`
+	FromLookup *struct {
+		Tag string `json:".tag"`
+	} `json:"from_lookup,omitempty"`
+	FromWrite *WriteError `json:"from_write,omitempty"`
+	To        *WriteError `json:"to,omitempty"`
+}
+
+// RelocationBatchResultEntry is the result of copying or moving one entry,
+// where Tag is "success" or "failure".
+type RelocationBatchResultEntry struct {
+	Tag     string                `json:".tag"`
+	Success *Metadata             `json:"success,omitempty"`
+	Failure *RelocationBatchError `json:"failure,omitempty"`
+}
+
+// CopyBatchInput request input.
+type CopyBatchInput struct {
+	Entries    []RelocationPath `json:"entries"`
+	AutoRename bool             `json:"autorename"`
+}
+
+// CopyBatchOutput request output. Tag is "complete" with the Entries in the
+// order requested, or "async_job_id" when the job must be polled with
+// CopyBatchCheckJobStatus.
+type CopyBatchOutput struct {
+	LaunchResult
+	Entries []*RelocationBatchResultEntry `json:"entries,omitempty"`
+}
+
+// CopyBatch copies up to 1000 files or folders at once. See
+// UploadDirOptions.Mute on notifications.
+func (c *Files) CopyBatch(in *CopyBatchInput) (out *CopyBatchOutput, err error) {
+	body, err := c.call("/files/copy_batch_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// CopyBatchCheckJobStatusInput request input.
+type CopyBatchCheckJobStatusInput = AsyncJobID
+
+// CopyBatchCheckJobStatusOutput request output. Tag is "in_progress" or
+// "complete" with the Entries.
+type CopyBatchCheckJobStatusOutput struct {
+	Tag     string                        `json:".tag"`
+	Entries []*RelocationBatchResultEntry `json:"entries,omitempty"`
+}
+
+// CopyBatchCheckJobStatus returns the status of an asynchronous CopyBatch job.
+func (c *Files) CopyBatchCheckJobStatus(in *CopyBatchCheckJobStatusInput) (out *CopyBatchCheckJobStatusOutput, err error) {
+	body, err := c.call("/files/copy_batch/check_job_status_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// relocationBatchLimit is the maximum number of entries in a copy or move batch.
+const relocationBatchLimit = 1000
+
+// CopyToMany copies the file or folder at src to each of dests, in batches
+// of up to 1000, returning the result for each destination in the same
+// order. Polling for the batches to complete stops when the client's
+// context is done.
+func (c *Files) CopyToMany(src string, dests []string) ([]*RelocationBatchResultEntry, error) {
+	var results []*RelocationBatchResultEntry
+
+	for i := 0; i < len(dests); i += relocationBatchLimit {
+		j := i + relocationBatchLimit
+		if j > len(dests) {
+			j = len(dests)
+		}
+
+		in := &CopyBatchInput{}
+		for _, dest := range dests[i:j] {
+			in.Entries = append(in.Entries, RelocationPath{FromPath: src, ToPath: dest})
+		}
+
+		entries, err := c.CopyBatchWait(in, nil)
+		if err == nil && len(entries) != len(in.Entries) {
+			err = fmt.Errorf("dropbox: copying files: %d results for %d paths", len(entries), len(in.Entries))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, entries...)
+	}
+
+	return results, nil
+}
+
+// CopyBatchWait copies a batch as CopyBatch does, polling until it completes
+// or the client's context is done, and returns the result for each entry in
+// the same order.
+func (c *Files) CopyBatchWait(in *CopyBatchInput, opts *PollOptions) ([]*RelocationBatchResultEntry, error) {
+	out, err := c.CopyBatch(in)
+	if err != nil {
+		return nil, err
+	}
+
+	switch out.Tag {
+	case "complete":
+		return out.Entries, nil
+	case "async_job_id":
+	default:
+		return nil, fmt.Errorf("dropbox: copying files: %s", out.Tag)
+	}
+
+	var entries []*RelocationBatchResultEntry
+	err = c.PollJob(func() (bool, error) {
+		status, err := c.CopyBatchCheckJobStatus(out.Job())
+		if err != nil {
+			return false, err
+		}
+
+		switch status.Tag {
+		case "in_progress":
+			return false, nil
+		case "complete":
+			entries = status.Entries
+			return true, nil
+		default:
+			return false, fmt.Errorf("dropbox: copying files: %s", status.Tag)
+		}
+	}, opts)
+
+	return entries, err
+}
+
+// MoveInput request input.
+type MoveInput struct {
+	FromPath               string `json:"from_path"`
+	ToPath                 string `json:"to_path"`
+	AllowSharedFolder      bool   `json:"allow_shared_folder,omitempty"`
+	AutoRename             bool   `json:"autorename,omitempty"`
+	AllowOwnershipTransfer bool   `json:"allow_ownership_transfer,omitempty"`
+}
+
+// MoveOutput request output.
+type MoveOutput struct {
+	Metadata
+}
+
+// Move a file or folder to a different location.
+func (c *Files) Move(in *MoveInput) (out *MoveOutput, err error) {
+	body, err := c.call("/files/move_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	var res relocationResult
+	if err = decode(body, &res); err != nil {
+		return
+	}
+
+	out = &MoveOutput{res.Metadata}
+	return
+}
+
+// MoveOut moves a file or folder out of a shared folder you do not own,
+// allowing ownership of the content to be transferred to you.
+func (c *Files) MoveOut(srcPath, destPath string) (out *MoveOutput, err error) {
+	return c.Move(&MoveInput{
+		FromPath:               srcPath,
+		ToPath:                 destPath,
+		AllowOwnershipTransfer: true,
+	})
+}
+
+// MoveBatchInput request input.
+type MoveBatchInput struct {
+	Entries                []RelocationPath `json:"entries"`
+	AutoRename             bool             `json:"autorename"`
+	AllowOwnershipTransfer bool             `json:"allow_ownership_transfer,omitempty"`
+}
+
+// MoveBatchOutput request output. Tag is "complete" with the Entries in the
+// order requested, or "async_job_id" when the job must be polled with
+// MoveBatchCheckJobStatus.
+type MoveBatchOutput struct {
+	LaunchResult
+	Entries []*RelocationBatchResultEntry `json:"entries,omitempty"`
+}
+
+// MoveBatch moves up to 1000 files or folders at once. See
+// UploadDirOptions.Mute on notifications.
+func (c *Files) MoveBatch(in *MoveBatchInput) (out *MoveBatchOutput, err error) {
+	body, err := c.call("/files/move_batch_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// MoveBatchCheckJobStatusInput request input.
+type MoveBatchCheckJobStatusInput = AsyncJobID
+
+// MoveBatchCheckJobStatusOutput request output. Tag is "in_progress" or
+// "complete" with the Entries.
+type MoveBatchCheckJobStatusOutput struct {
+	Tag     string                        `json:".tag"`
+	Entries []*RelocationBatchResultEntry `json:"entries,omitempty"`
+}
+
+// MoveBatchCheckJobStatus returns the status of an asynchronous MoveBatch job.
+func (c *Files) MoveBatchCheckJobStatus(in *MoveBatchCheckJobStatusInput) (out *MoveBatchCheckJobStatusOutput, err error) {
+	body, err := c.call("/files/move_batch/check_job_status_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// MoveBatchWait moves a batch as MoveBatch does, polling until it completes
+// or the client's context is done, and returns the result for each entry in
+// the same order.
+func (c *Files) MoveBatchWait(in *MoveBatchInput, opts *PollOptions) ([]*RelocationBatchResultEntry, error) {
+	out, err := c.MoveBatch(in)
+	if err != nil {
+		return nil, err
+	}
+
+	switch out.Tag {
+	case "complete":
+		return out.Entries, nil
+	case "async_job_id":
+	default:
+		return nil, fmt.Errorf("dropbox: moving files: %s", out.Tag)
+	}
+
+	var entries []*RelocationBatchResultEntry
+	err = c.PollJob(func() (bool, error) {
+		status, err := c.MoveBatchCheckJobStatus(out.Job())
+		if err != nil {
+			return false, err
+		}
+
+		switch status.Tag {
+		case "in_progress":
+			return false, nil
+		case "complete":
+			entries = status.Entries
+			return true, nil
+		default:
+			return false, fmt.Errorf("dropbox: moving files: %s", status.Tag)
+		}
+	}, opts)
+
+	return entries, err
+}
+
+// MoveAsync moves a file or folder as Move does, but as a batch of one entry
+// so that moving a large folder does not time out. Move has no asynchronous
+// mode. When the returned Tag is "async_job_id" the job is polled with
+// MoveBatchCheckJobStatus, or use MoveWait.
+func (c *Files) MoveAsync(in *MoveInput) (*MoveBatchOutput, error) {
+	return c.MoveBatch(in.batch())
+}
+
+// batch returns the move as a batch of one entry.
+func (in *MoveInput) batch() *MoveBatchInput {
+	return &MoveBatchInput{
+		Entries:                []RelocationPath{{FromPath: in.FromPath, ToPath: in.ToPath}},
+		AutoRename:             in.AutoRename,
+		AllowOwnershipTransfer: in.AllowOwnershipTransfer,
+	}
+}
+
+// MoveWait moves a file or folder with MoveAsync, polling until the move
+// completes. A failed move returns its *RelocationBatchError.
+func (c *Files) MoveWait(in *MoveInput, opts *PollOptions) (*MoveOutput, error) {
+	entries, err := c.MoveBatchWait(in.batch(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("dropbox: moving file: %d results", len(entries))
+	}
+
+	switch e := entries[0]; {
+	case e.Failure != nil:
+		return nil, e.Failure
+	case e.Success != nil:
+		return &MoveOutput{*e.Success}, nil
+	default:
+		return nil, fmt.Errorf("dropbox: moving file: %s", e.Tag)
+	}
+}
+
+// RestoreInput request input.
+type RestoreInput struct {
+	Path string `json:"path"`
+	Rev  string `json:"rev"`
+}
+
+// RestoreOutput request output.
+type RestoreOutput struct {
+	Metadata
+}
+
+// Restore a file to a specific revision.
+func (c *Files) Restore(in *RestoreInput) (out *RestoreOutput, err error) {
+	body, err := c.call("/files/restore", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFolderInput request input.
+type ListFolderInput struct {
+	Path             string `json:"path"`
+	Recursive        bool   `json:"recursive"`
+	IncludeMediaInfo bool   `json:"include_media_info"`
+	IncludeDeleted   bool   `json:"include_deleted"`
+
+	// Limit is the approximate maximum number of entries in each page, from
+	// 1 to 2000, defaulting to the server's page size.
+	Limit uint64 `json:"limit,omitempty"`
+
+	// SharedLink lists the folder of a shared link, with Path relative to it.
+	SharedLink *SharedLink `json:"shared_link,omitempty"`
+
+	// IncludePropertyGroups returns the PropertyGroups matching the filter.
+	IncludePropertyGroups *TemplateFilter `json:"include_property_groups,omitempty"`
+}
+
+// SharedLink identifies a shared link, with the Password of a password
+// protected link.
+type SharedLink struct {
+	URL      string `json:"url"`
+	Password string `json:"password,omitempty"`
+}
+
+// ListFolderOutput request output.
+type ListFolderOutput struct {
+	Cursor  string `json:"cursor"`
+	HasMore bool   `json:"has_more"`
+	Entries []*Metadata
+}
+
+// ListFolder returns the metadata for a file or folder.
+func (c *Files) ListFolder(in *ListFolderInput) (out *ListFolderOutput, err error) {
+	return c.listFolder(c.context(), in)
+}
+
+// listFolder implements ListFolder with a context.
+func (c *Files) listFolder(ctx context.Context, in *ListFolderInput) (out *ListFolderOutput, err error) {
+	in.Path = normalizePath(in.Path)
+
+	body, err := c.callContext(ctx, "/files/list_folder", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFolderContinueInput request input.
+type ListFolderContinueInput struct {
+	Cursor string `json:"cursor"`
+}
+
+// ListFolderContinue pagenates using the cursor from ListFolder, returning
+// ErrEmptyCursor if the cursor is empty, or ErrCursorReset if it has been
+// reset and the folder must be listed again.
+func (c *Files) ListFolderContinue(in *ListFolderContinueInput) (out *ListFolderOutput, err error) {
+	return c.listFolderContinue(c.context(), in)
+}
+
+// listFolderContinue implements ListFolderContinue with a context.
+func (c *Files) listFolderContinue(ctx context.Context, in *ListFolderContinueInput) (out *ListFolderOutput, err error) {
+	if in.Cursor == "" {
+		return nil, ErrEmptyCursor
+	}
+
+	body, err := c.callContext(ctx, "/files/list_folder/continue", in)
+	if IsCursorReset(err) {
+		return nil, ErrCursorReset
+	}
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFolderAll lists every entry of the folder, following the cursor until
+// there are no more. On error the entries listed so far are returned with it.
+func (c *Files) ListFolderAll(in *ListFolderInput) ([]*Metadata, error) {
+	out, err := c.ListFolder(in)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := out.Entries
+	for out.HasMore {
+		out, err = c.ListFolderContinue(&ListFolderContinueInput{Cursor: out.Cursor})
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, out.Entries...)
+	}
+
+	return entries, nil
+}
+
+// Walk calls fn for each entry below path, including folders, as each page
+// of the recursive listing is returned. Walking stops at the first error,
+// including one returned by fn, which is returned.
+func (c *Files) Walk(path string, fn func(*Metadata) error) error {
+	out, err := c.ListFolder(&ListFolderInput{Path: path, Recursive: true})
+
+	for err == nil {
+		for _, entry := range out.Entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if !out.HasMore {
+			return nil
+		}
+
+		out, err = c.ListFolderContinue(&ListFolderContinueInput{Cursor: out.Cursor})
+	}
+
+	return err
+}
+
+// ListFolderGetLatestCursorOutput request output.
+type ListFolderGetLatestCursorOutput struct {
+	Cursor string `json:"cursor"`
+}
+
+// ListFolderGetLatestCursor returns a cursor for the current state of the
+// folder described by in, without listing it. Passing it to
+// ListFolderContinue later returns only the changes since.
+func (c *Files) ListFolderGetLatestCursor(in *ListFolderInput) (out *ListFolderGetLatestCursorOutput, err error) {
+	in.Path = normalizePath(in.Path)
+
+	body, err := c.call("/files/list_folder/get_latest_cursor", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFolderLongpollInput request input. Timeout is in seconds, between 30
+// and 480, defaulting to 30.
+type ListFolderLongpollInput struct {
+	Cursor  string `json:"cursor"`
+	Timeout uint64 `json:"timeout,omitempty"`
+}
+
+// ListFolderLongpollOutput request output. Backoff is the number of seconds
+// to wait before calling ListFolderLongpoll again, when set.
+type ListFolderLongpollOutput struct {
+	Changes bool   `json:"changes"`
+	Backoff uint64 `json:"backoff,omitempty"`
+}
+
+// ListFolderLongpoll blocks until there are changes to the folder listed by
+// the cursor from ListFolder or ListFolderContinue, or the timeout elapses.
+// ErrCursorReset is returned if the cursor has been reset.
+func (c *Files) ListFolderLongpoll(in *ListFolderLongpollInput) (out *ListFolderLongpollOutput, err error) {
+	return c.listFolderLongpoll(c.context(), in)
+}
+
+// listFolderLongpoll implements ListFolderLongpoll with a context.
+func (c *Files) listFolderLongpoll(ctx context.Context, in *ListFolderLongpollInput) (out *ListFolderLongpollOutput, err error) {
+	body, err := c.notify(ctx, "/files/list_folder/longpoll", in)
+	if IsCursorReset(err) {
+		return nil, ErrCursorReset
+	}
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// Watch longpolls for changes to the folder listed by cursor, sending each
+// page of changed entries on the returned channel. The backoff requested by
+// Dropbox is respected and failed requests are retried, backing off up to a
+// minute. The returned function stops watching and closes the channel.
+//
+// When Dropbox resets the cursor a nil page is sent and the channel closed,
+// as the folder cannot be listed again from the cursor alone. Use
+// WatchFolder to resync automatically.
+func (c *Files) Watch(cursor string) (<-chan []*Metadata, func()) {
+	return c.startWatch(func(ctx context.Context, ch chan<- []*Metadata) {
+		c.watch(ctx, nil, cursor, ch)
+	})
+}
+
+// WatchFolder lists the folder described by in, sending each page of entries
+// on the returned channel, and then watches it for changes as Watch does.
+//
+// When Dropbox resets the cursor a nil page is sent, followed by the complete
+// listing again, so consumers should discard any state they have built up
+// when receiving a nil page.
+func (c *Files) WatchFolder(in *ListFolderInput) (<-chan []*Metadata, func()) {
+	return c.startWatch(func(ctx context.Context, ch chan<- []*Metadata) {
+		cursor, ok := c.watchList(ctx, in, ch)
+		if ok {
+			c.watch(ctx, in, cursor, ch)
+		}
+	})
+}
+
+// startWatch runs fn in a goroutine until the returned function is called,
+// with a context derived from the client's.
+func (c *Files) startWatch(fn func(ctx context.Context, ch chan<- []*Metadata)) (<-chan []*Metadata, func()) {
+	ctx, cancel := context.WithCancel(c.context())
+	ch := make(chan []*Metadata)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer close(ch)
+		fn(ctx, ch)
+	}()
+
+	return ch, func() {
+		cancel()
+		<-done
+	}
+}
+
+// watchSleep sleeps for d, returning false if ctx is cancelled first.
+func watchSleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// watchBackoff returns a function which sleeps for an exponentially
+// increasing duration up to a minute, and one which resets it.
+func watchBackoff(ctx context.Context) (fail func() bool, reset func()) {
+	retry := time.Second
+
+	fail = func() bool {
+		ok := watchSleep(ctx, retry)
+		if retry < time.Minute {
+			retry *= 2
+		}
+		return ok
+	}
+
+	reset = func() {
+		retry = time.Second
+	}
+
+	return
+}
+
+// watchSend sends page on ch, returning false if ctx is cancelled first.
+func watchSend(ctx context.Context, ch chan<- []*Metadata, page []*Metadata) bool {
+	select {
+	case ch <- page:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// watchList lists the folder described by in from scratch, sending each page
+// and retrying failed requests, and returns the resulting cursor.
+func (c *Files) watchList(ctx context.Context, in *ListFolderInput, ch chan<- []*Metadata) (string, bool) {
+	fail, reset := watchBackoff(ctx)
+
+list:
+	for {
+		out, err := c.listFolder(ctx, in)
+		if err != nil {
+			if !fail() {
+				return "", false
+			}
+			continue
+		}
+		reset()
+
+		for {
+			if len(out.Entries) > 0 && !watchSend(ctx, ch, out.Entries) {
+				return "", false
+			}
+
+			if !out.HasMore {
+				return out.Cursor, true
+			}
+
+			cursor := out.Cursor
+			for {
+				out, err = c.listFolderContinue(ctx, &ListFolderContinueInput{Cursor: cursor})
+				if err == ErrCursorReset {
+					if !watchSend(ctx, ch, nil) {
+						return "", false
+					}
+					continue list
+				}
+				if err == nil {
+					break
+				}
+				if !fail() {
+					return "", false
+				}
+			}
+			reset()
+		}
+	}
+}
+
+// watch implements Watch until ctx is cancelled, relisting in when the
+// cursor is reset, or stopping if in is nil.
+func (c *Files) watch(ctx context.Context, in *ListFolderInput, cursor string, ch chan<- []*Metadata) {
+	fail, reset := watchBackoff(ctx)
+
+	resync := func() bool {
+		if !watchSend(ctx, ch, nil) || in == nil {
+			return false
+		}
+
+		var ok bool
+		cursor, ok = c.watchList(ctx, in, ch)
+		return ok
+	}
+
+	for ctx.Err() == nil {
+		poll, err := c.listFolderLongpoll(ctx, &ListFolderLongpollInput{
+			Cursor:  cursor,
+			Timeout: 120,
+		})
+		if err == ErrCursorReset {
+			if !resync() {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			if !fail() {
+				return
+			}
+			continue
+		}
+
+		for poll.Changes {
+			out, err := c.listFolderContinue(ctx, &ListFolderContinueInput{Cursor: cursor})
+			if err == ErrCursorReset {
+				if !resync() {
+					return
+				}
+				break
+			}
+			if err != nil {
+				if !fail() {
+					return
+				}
+				continue
+			}
+
+			cursor = out.Cursor
+			reset()
+
+			if len(out.Entries) > 0 && !watchSend(ctx, ch, out.Entries) {
+				return
+			}
+
+			poll.Changes = out.HasMore
+		}
+
+		if poll.Backoff > 0 && !watchSleep(ctx, time.Duration(poll.Backoff)*time.Second) {
+			return
+		}
+	}
+}
+
+// WaitVisible polls the listing of the parent folder of m, such as a file
+// just uploaded, until it includes an entry with the same ID. ErrNotVisible
+// is returned if this does not happen within timeout, or the context's error
+// once the client's context is done. Listings are eventually consistent, so
+// this is useful before relying on ListFolder.
+func (c *Files) WaitVisible(m *Metadata, timeout time.Duration) error {
+	ctx := c.context()
+	deadline := time.Now().Add(timeout)
+	delay := 100 * time.Millisecond
+
+	for {
+		ok, err := c.listed(path.Dir(m.PathLower), m.ID)
+		if err != nil || ok {
+			return err
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return ErrNotVisible
+		}
+
+		if !watchSleep(ctx, delay) {
+			return ctx.Err()
+		}
+
+		if delay < 2*time.Second {
+			delay *= 2
+		}
+	}
+}
+
+// listed returns whether the folder at path contains an entry with the given ID.
+func (c *Files) listed(path, id string) (bool, error) {
+	out, err := c.ListFolder(&ListFolderInput{Path: path})
+
+	for err == nil {
+		for _, entry := range out.Entries {
+			if entry.ID == id {
+				return true, nil
+			}
+		}
+
+		if !out.HasMore {
+			return false, nil
+		}
+
+		out, err = c.ListFolderContinue(&ListFolderContinueInput{Cursor: out.Cursor})
+	}
+
+	return false, err
+}
+
+// ListModifiedSince lists the folder at path recursively, returning the files
+// whose server modified time is at or after since. Folders have no modified
+// time and are never returned.
+func (c *Files) ListModifiedSince(path string, since time.Time) ([]*Metadata, error) {
+	entries, err := c.ListFolderAll(&ListFolderInput{Path: path, Recursive: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*Metadata
+	for _, entry := range entries {
+		if entry.Tag == "file" && !entry.ServerModified.Before(since) {
+			files = append(files, entry)
+		}
+	}
+
+	return files, nil
+}
+
+// SearchMode determines how a search is performed.
+type SearchMode string
+
+// Supported search modes.
+const (
+	SearchModeFilename           SearchMode = "filename"
+	SearchModeFilenameAndContent            = "filename_and_content"
+	SearchModeDeletedFilename               = "deleted_filename"
+)
+
+// SearchMatchType represents the type of match made.
+type SearchMatchType string
+
+// Supported search match types.
+const (
+	SearchMatchFilename SearchMatchType = "filename"
+	SearchMatchContent                  = "content"
+	SearchMatchBoth                     = "both"
+)
+
+// SearchMatch represents a matched file or folder.
+type SearchMatch struct {
+	MatchType struct {
+		Tag SearchMatchType `json:".tag"`
+	} `json:"match_type"`
+	Metadata *Metadata `json:"metadata"`
+}
+
+// SearchInput request input.
+type SearchInput struct {
+	Path       string     `json:"path"`
+	Query      string     `json:"query"`
+	MaxResults uint64     `json:"max_results,omitempty"`
+	Mode       SearchMode `json:"mode"`
+
+	// Start is ignored, as search_v2 pages with a cursor.
+	//
+	// Deprecated: use SearchContinue with the output's Cursor.
+	Start uint64 `json:"start,omitempty"`
+}
+
+// SearchOutput request output. When More is true the next page is returned
+// by SearchContinue with the Cursor.
+type SearchOutput struct {
+	Matches []*SearchMatch `json:"matches"`
+	More    bool           `json:"more"`
+	Cursor  string         `json:"cursor,omitempty"`
+
+	// Deprecated: always zero, as search_v2 pages with the Cursor.
+	Start uint64 `json:"start"`
+}
+
+// SearchContinueInput request input.
+type SearchContinueInput struct {
+	Cursor string `json:"cursor"`
+}
+
+// Search for files and folders. The Mode defaults to SearchModeFilename.
+func (c *Files) Search(in *SearchInput) (*SearchOutput, error) {
+	opts := &SearchOptions{
+		Path:         normalizePath(in.Path),
+		MaxResults:   in.MaxResults,
+		FilenameOnly: in.Mode != SearchModeFilenameAndContent,
+	}
+
+	if in.Mode == SearchModeDeletedFilename {
+		opts.FileStatus = "deleted"
+	}
+
+	out, err := c.SearchV2(&SearchV2Input{Query: in.Query, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.searchOutput(), nil
+}
+
+// SearchContinue returns the next page of a Search.
+func (c *Files) SearchContinue(in *SearchContinueInput) (*SearchOutput, error) {
+	out, err := c.SearchV2Continue(in)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.searchOutput(), nil
+}
+
+// FileCategory restricts search_v2 results to a kind of file.
+type FileCategory string
+
+// Supported file categories.
+const (
+	FileCategoryImage        FileCategory = "image"
+	FileCategoryDocument     FileCategory = "document"
+	FileCategoryPDF          FileCategory = "pdf"
+	FileCategorySpreadsheet  FileCategory = "spreadsheet"
+	FileCategoryPresentation FileCategory = "presentation"
+	FileCategoryAudio        FileCategory = "audio"
+	FileCategoryVideo        FileCategory = "video"
+	FileCategoryFolder       FileCategory = "folder"
+	FileCategoryPaper        FileCategory = "paper"
+	FileCategoryOthers       FileCategory = "others"
+)
+
+// SearchOptions narrow a search_v2 query.
+type SearchOptions struct {
+	Path           string         `json:"path,omitempty"`
+	MaxResults     uint64         `json:"max_results,omitempty"`
+	FileStatus     string         `json:"file_status,omitempty"`
+	FilenameOnly   bool           `json:"filename_only,omitempty"`
+	FileExtensions []string       `json:"file_extensions,omitempty"`
+	FileCategories []FileCategory `json:"file_categories,omitempty"`
+}
+
+// SearchV2Input request input.
+type SearchV2Input struct {
+	Query   string         `json:"query"`
+	Options *SearchOptions `json:"options,omitempty"`
+}
+
+// SearchV2Match represents a matched file or folder.
+type SearchV2Match struct {
+	MatchType *struct {
+		Tag string `json:".tag"`
+	} `json:"match_type,omitempty"`
+	Metadata struct {
+		Tag      string    `json:".tag"`
+		Metadata *Metadata `json:"metadata"`
+	} `json:"metadata"`
+}
+
+// SearchV2Output request output.
+type SearchV2Output struct {
+	Matches []*SearchV2Match `json:"matches"`
+	HasMore bool             `json:"has_more"`
+	Cursor  string           `json:"cursor,omitempty"`
+}
+
+// SearchV2 searches for files and folders, optionally restricted to file
+// categories or extensions with the Options.
+func (c *Files) SearchV2(in *SearchV2Input) (out *SearchV2Output, err error) {
+	if in.Options != nil {
+		in.Options.Path = normalizePath(in.Options.Path)
+	}
+
+	body, err := c.call("/files/search_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// SearchV2Continue returns the next page of a SearchV2, returning
+// ErrEmptyCursor when there is no cursor.
+func (c *Files) SearchV2Continue(in *SearchContinueInput) (out *SearchV2Output, err error) {
+	if in.Cursor == "" {
+		return nil, ErrEmptyCursor
+	}
+
+	body, err := c.call("/files/search/continue_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// searchOutput converts a search_v2 page to a SearchOutput.
+func (out *SearchV2Output) searchOutput() *SearchOutput {
+	s := &SearchOutput{More: out.HasMore, Cursor: out.Cursor}
+
+	for _, m := range out.Matches {
+		match := &SearchMatch{Metadata: m.Metadata.Metadata}
+		if m.MatchType != nil {
+			match.MatchType.Tag = SearchMatchType(m.MatchType.Tag)
+		}
+		s.Matches = append(s.Matches, match)
+	}
+
+	return s
+}
+
+// DownloadSearchResultsZip searches for files, writing every matching file to
+// w as a zip archive in which each file is named by its path. Files are
+// zipped as they are downloaded, so nothing is staged in Dropbox or on disk.
+// It returns the number of files zipped.
+func (c *Files) DownloadSearchResultsZip(in *SearchInput, w io.Writer) (int, error) {
+	z := zip.NewWriter(w)
+	n := 0
+
+	out, err := c.Search(in)
+	if err != nil {
+		return n, err
+	}
+
+	for {
+		for _, match := range out.Matches {
+			if match.Metadata == nil || match.Metadata.Tag != "file" {
+				continue
+			}
+
+			if err := c.zipFile(z, match.Metadata); err != nil {
+				return n, err
+			}
+			n++
+		}
+
+		if !out.More {
+			break
+		}
+
+		if out, err = c.SearchContinue(&SearchContinueInput{out.Cursor}); err != nil {
+			return n, err
+		}
+	}
+
+	return n, z.Close()
+}
+
+// zipFile downloads the file m and adds it to z.
+func (c *Files) zipFile(z *zip.Writer, m *Metadata) error {
+	out, err := c.Download(&DownloadInput{Path: m.PathLower})
+	if err != nil {
+		return err
+	}
+	defer out.Body.Close()
+
+	w, err := z.CreateHeader(&zip.FileHeader{
+		Name:     strings.TrimPrefix(m.PathDisplay, "/"),
+		Method:   zip.Deflate,
+		Modified: m.ClientModified,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, out.Body)
+	return err
+}
+
+// timeFormat is the format of timestamps sent to Dropbox, which must be in
+// UTC without fractional seconds.
+const timeFormat = "2006-01-02T15:04:05Z"
+
+// FormatTime formats t as Dropbox requires, such as for the ClientModified
+// of an upload.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// normalizeTime converts an RFC 3339 timestamp to the format Dropbox
+// requires, leaving other strings as is for Dropbox to reject.
+func normalizeTime(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s
+	}
+	return FormatTime(t)
+}
+
+// UploadInput request input. ClientModified is an RFC 3339 timestamp, which
+// is converted to UTC and truncated to whole seconds, and may be formatted
+// with FormatTime.
+type UploadInput struct {
+	Path           string    `json:"path"`
+	Mode           WriteMode `json:"mode"`
+	AutoRename     bool      `json:"autorename"`
+	Mute           bool      `json:"mute"`
+	ClientModified string    `json:"client_modified,omitempty"`
+	Reader         io.Reader `json:"-"`
+
+	// PropertyGroups are attached to the file as it is committed.
+	PropertyGroups []*PropertyGroup `json:"property_groups,omitempty"`
+
+	// Dedupe replaces autorename with a deterministic name when the path
+	// already exists with different content, so that retrying an upload of
+	// the same content returns the existing file rather than a duplicate.
+	// Only applies to WriteModeAdd.
+	Dedupe DedupeFunc `json:"-"`
+
+	// CreateParents creates the parent folder with MkdirAll and retries the
+	// upload once when Dropbox reports it is missing. Unless Reader is an
+	// io.Seeker it is buffered in memory so that it can be replayed.
+	CreateParents bool `json:"-"`
+
+	// ExpectRev overwrites the file only if its current rev is ExpectRev,
+	// replacing Mode with WriteModeUpdate(ExpectRev).
+	ExpectRev string `json:"-"`
+
+	// ProgressFunc is called as the Reader is read while uploading.
+	ProgressFunc ProgressFunc `json:"-"`
+
+	// Size is the number of bytes the Reader will return, sent as the
+	// Content-Length of a stream whose length cannot otherwise be determined.
+	// Without it such streams are sent with chunked transfer encoding. The
+	// request fails if the Reader returns a different number of bytes.
+	Size int64 `json:"-"`
+}
+
+// ProgressFunc is called with the number of bytes written so far and the
+// total, which is -1 when it is not known, such as for a stream.
+type ProgressFunc func(written, total int64)
+
+// reader returns the upload's Reader, reporting progress if requested.
+func (in *UploadInput) reader() (io.Reader, error) {
+	r := in.Reader
+	if _, ok := r.(io.Seeker); !ok && in.Size > 0 {
+		r = &sizedReader{r, in.Size}
+	}
+
+	if in.ProgressFunc == nil {
+		return r, nil
+	}
+	return progressReader(r, in.ProgressFunc)
+}
+
+// sizedReader is a stream of a known length.
+type sizedReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implementation.
+func (s *sizedReader) Read(b []byte) (int, error) {
+	n, err := s.r.Read(b)
+	s.n -= int64(n)
+	return n, err
+}
+
+// Len returns the number of bytes remaining.
+func (s *sizedReader) Len() int {
+	return int(s.n)
+}
+
+// progress counts the bytes read from a reader.
+type progress struct {
+	r       io.Reader
+	fn      ProgressFunc
+	written int64
+	total   int64
+}
+
+// Read implementation.
+func (p *progress) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.written += int64(n)
+		p.fn(p.written, p.total)
+	}
+	return n, err
+}
+
+// Len returns the number of bytes remaining, or -1 if it is not known.
+func (p *progress) Len() int {
+	if p.total < 0 {
+		return -1
+	}
+	return int(p.total - p.written)
+}
+
+// progressSeeker is a progress which may be rewound when a request is
+// retried, so that the count restarts.
+type progressSeeker struct {
+	*progress
+	start int64
+}
+
+// Seek implementation.
+func (p *progressSeeker) Seek(offset int64, whence int) (int64, error) {
+	n, err := p.r.(io.Seeker).Seek(offset, whence)
+	if err == nil {
+		p.written = n - p.start
+	}
+	return n, err
+}
+
+// progressReader wraps r to call fn as it is read. A seekable r remains
+// seekable.
+func progressReader(r io.Reader, fn ProgressFunc) (io.Reader, error) {
+	total, err := readerSize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &progress{r: r, fn: fn, total: total}
+
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return p, nil
+	}
+
+	start, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &progressSeeker{p, start}, nil
+}
+
+// readerSize returns the number of bytes remaining in r, or -1 if it cannot
+// be determined without reading it.
+func readerSize(r io.Reader) (int64, error) {
+	if l, ok := r.(interface{ Len() int }); ok {
+		return int64(l.Len()), nil
+	}
+
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return -1, nil
+	}
+
+	start, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return end - start, nil
+}
+
+// RevConflictError is returned by Upload in WriteModeUpdate, or Delete with
+// a ParentRev, when the file's rev no longer matches, such as when it was
+// changed concurrently.
+type RevConflictError struct {
+	Path      string
+	ExpectRev string
+
+	// Rev is the current rev of the file, or empty if it is not a file.
+	Rev string
+}
+
+// Error implementation.
+func (e *RevConflictError) Error() string {
+	if e.Rev == "" {
+		return fmt.Sprintf("dropbox: %s is no longer at rev %s", e.Path, e.ExpectRev)
+	}
+	return fmt.Sprintf("dropbox: %s is at rev %s, expected %s", e.Path, e.Rev, e.ExpectRev)
+}
+
+// DedupeFunc returns the path to upload to when path already exists with
+// different content, given the content hash of the upload.
+type DedupeFunc func(path, contentHash string) string
+
+// ContentHashSuffix is a DedupeFunc suffixing the file name with the start
+// of the content hash, for example "/a/b.txt" becomes "/a/b (1a2b3c4d).txt".
+func ContentHashSuffix(p, contentHash string) string {
+	ext := path.Ext(p)
+	return fmt.Sprintf("%s (%.8s)%s", strings.TrimSuffix(p, ext), contentHash, ext)
+}
+
+// UploadOutput request output.
+type UploadOutput struct {
+	Metadata
+
+	// Renamed is true when AutoRename uploaded the file under a different
+	// name than the Path, such as "a (1).txt".
+	Renamed bool `json:"-"`
+}
+
+// Upload a file smaller than 150MB. Use UploadLarge for larger files.
+//
+// The Reader is streamed rather than buffered in memory, unless Dedupe is
+// set, or CreateParents and it is not an io.Seeker.
+func (c *Files) Upload(in *UploadInput) (out *UploadOutput, err error) {
+	in.Path = normalizePath(in.Path)
+	in.ClientModified = normalizeTime(in.ClientModified)
+
+	if in.ExpectRev != "" {
+		upload := *in
+		upload.Mode = WriteModeUpdate(in.ExpectRev)
+		upload.ExpectRev = ""
+		return c.Upload(&upload)
+	}
+
+	if in.Dedupe != nil && (in.Mode == "" || in.Mode == WriteModeAdd) {
+		return c.uploadDedupe(in)
+	}
+
+	if in.CreateParents {
+		return c.uploadCreateParents(in)
+	}
+
+	if in.Mode.Rev() != "" {
+		return c.uploadUpdate(in)
+	}
+
+	r, err := in.reader()
+	if err != nil {
+		return
+	}
+
+	body, _, err := c.download("/files/upload", in, r)
+	if err != nil {
+		return nil, in.conflictError(err)
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	if err == nil {
+		out.Renamed = in.renamed(&out.Metadata)
+	}
+	return
+}
+
+// UploadOption sets a field of the UploadInput of UploadWith.
+type UploadOption func(*UploadInput)
+
+// WithMode sets the WriteMode of an upload.
+func WithMode(mode WriteMode) UploadOption {
+	return func(in *UploadInput) {
+		in.Mode = mode
+	}
+}
+
+// WithAutoRename renames an upload which conflicts with an existing file.
+func WithAutoRename() UploadOption {
+	return func(in *UploadInput) {
+		in.AutoRename = true
+	}
+}
+
+// WithMute does not notify desktop clients of an upload.
+func WithMute() UploadOption {
+	return func(in *UploadInput) {
+		in.Mute = true
+	}
+}
+
+// WithClientModified sets the modification time of an upload.
+func WithClientModified(t time.Time) UploadOption {
+	return func(in *UploadInput) {
+		in.ClientModified = FormatTime(t)
+	}
+}
+
+// WithProgress reports the progress of an upload to fn.
+func WithProgress(fn ProgressFunc) UploadOption {
+	return func(in *UploadInput) {
+		in.ProgressFunc = fn
+	}
+}
+
+// UploadWith uploads r to path as Upload does, with the UploadInput set by
+// opts, such as:
+//
+//	c.Files.UploadWith("/a.txt", r, dropbox.WithAutoRename(), dropbox.WithMute())
+func (c *Files) UploadWith(path string, r io.Reader, opts ...UploadOption) (*UploadOutput, error) {
+	in := &UploadInput{Path: path, Reader: r}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return c.Upload(in)
+}
+
+// uploadUpdate uploads in update mode, returning a *RevConflictError if the
+// file is no longer at the rev.
+func (c *Files) uploadUpdate(in *UploadInput) (out *UploadOutput, err error) {
+	r, err := in.reader()
+	if err != nil {
+		return
+	}
+
+	body, _, err := c.download("/files/upload", in, r)
+	if hasTag(err, "path/conflict") {
+		conflict, cerr := c.UploadConflictOf(err, in.Path)
+		if cerr != nil {
+			return nil, cerr
+		}
+		return nil, &RevConflictError{Path: in.Path, ExpectRev: in.Mode.Rev(), Rev: conflict.Rev}
+	}
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// uploadCreateParents uploads, creating the parent folder and retrying once
+// if it does not exist.
+func (c *Files) uploadCreateParents(in *UploadInput) (*UploadOutput, error) {
+	upload := *in
+	upload.CreateParents = false
+
+	r, ok := in.Reader.(io.ReadSeeker)
+	if !ok {
+		data, err := ioutil.ReadAll(in.Reader)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
+	upload.Reader = r
+	out, err := c.Upload(&upload)
+	if !hasTag(err, "path/not_found") {
+		return out, err
+	}
+
+	if err := c.MkdirAll(path.Dir(in.Path)); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return c.Upload(&upload)
+}
+
+// UploadConflict describes an upload which conflicted with an existing file
+// or folder.
+type UploadConflict struct {
+	// Conflict is "file", "folder" or "file_ancestor".
+	Conflict string
+
+	// Rev is the current rev of the conflicting file on the server.
+	Rev string
+
+	// UploadSessionID of the failed commit, if it was an upload session.
+	UploadSessionID string
+}
+
+// uploadError is the error union returned by upload endpoints.
+type uploadError struct {
+	Tag    string `json:".tag"`
+	Reason struct {
+		Tag      string `json:".tag"`
+		Conflict struct {
+			Tag string `json:".tag"`
+		} `json:"conflict"`
+	} `json:"reason"`
+	UploadSessionID string `json:"upload_session_id"`
+}
+
+// UploadConflictOf returns the details of a conflict reported by an upload
+// to path, or nil if err is not a conflict. For a file conflict the file's
+// current rev is fetched, so callers can merge and retry with WriteModeUpdate.
+func (c *Files) UploadConflictOf(err error, path string) (*UploadConflict, error) {
+	e, ok := asError(err)
+	if !ok || !hasTag(err, "path/conflict") {
+		return nil, nil
+	}
+
+	conflict, err := uploadConflictOf(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if conflict.Conflict == "file" {
+		out, err := c.GetMetadata(&GetMetadataInput{Path: path})
+		if err != nil {
+			return nil, err
+		}
+		conflict.Rev = out.Rev
+	}
+
+	return conflict, nil
+}
+
+// uploadConflictOf returns the conflict reported by e, without fetching the
+// rev of a conflicting file.
+func uploadConflictOf(e *Error) (*UploadConflict, error) {
+	var details uploadError
+	if len(e.Details) > 0 {
+		if err := json.Unmarshal(e.Details, &details); err != nil {
+			return nil, err
+		}
+	}
+
+	conflict := &UploadConflict{
+		Conflict:        details.Reason.Conflict.Tag,
+		UploadSessionID: details.UploadSessionID,
+	}
+
+	if tags := strings.Split(e.Summary, "/"); conflict.Conflict == "" && len(tags) > 2 {
+		conflict.Conflict = tags[2]
+	}
+
+	return conflict, nil
+}
+
+// PathConflictError is returned by uploads in WriteModeAdd without
+// AutoRename when a file or folder already exists at the Path. It wraps the
+// *Error, so IsConflict and UploadConflictOf apply to it.
+type PathConflictError struct {
+	Path string
+
+	// Conflict is "file", "folder" or "file_ancestor".
+	Conflict string
+
+	Err *Error
+}
+
+// Error string.
+func (e *PathConflictError) Error() string {
+	return fmt.Sprintf("dropbox: %s conflicts with an existing %s", e.Path, strings.Replace(e.Conflict, "_", " ", -1))
+}
+
+// Unwrap returns the *Error.
+func (e *PathConflictError) Unwrap() error {
+	return e.Err
+}
+
+// conflictError returns err as a *PathConflictError if it is a conflict
+// which was not avoided by renaming.
+func (in *UploadInput) conflictError(err error) error {
+	if in.AutoRename || (in.Mode != "" && in.Mode != WriteModeAdd) || !hasTag(err, "path/conflict") {
+		return err
+	}
+
+	e, _ := asError(err)
+	conflict, cerr := uploadConflictOf(e)
+	if cerr != nil {
+		return err
+	}
+
+	return &PathConflictError{Path: in.Path, Conflict: conflict.Conflict, Err: e}
+}
+
+// output returns the UploadOutput of the upload of m.
+func (in *UploadInput) output(m Metadata) *UploadOutput {
+	return &UploadOutput{Metadata: m, Renamed: in.renamed(&m)}
+}
+
+// renamed returns whether the file was uploaded as m under a different name
+// than requested, because of AutoRename.
+func (in *UploadInput) renamed(m *Metadata) bool {
+	if !in.AutoRename || !strings.HasPrefix(in.Path, "/") || m.Name == "" {
+		return false
+	}
+	return !strings.EqualFold(path.Base(in.Path), m.Name)
+}
+
+// UploadLarge uploads a file of any size in 8MB chunks with an upload
+// session. The Dedupe and CreateParents options are not supported.
+func (c *Files) UploadLarge(in *UploadInput) (*UploadOutput, error) {
+	r, err := in.reader()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.uploadStream(r, in.commit())
+	if err != nil {
+		return nil, in.conflictError(err)
+	}
+
+	return in.output(out.Metadata), nil
+}
+
+// commit returns the CommitInfo of an upload in a session.
+func (in *UploadInput) commit() CommitInfo {
+	mode := in.Mode
+	if in.ExpectRev != "" {
+		mode = WriteModeUpdate(in.ExpectRev)
+	}
+
+	return CommitInfo{
+		Path:           in.Path,
+		Mode:           mode,
+		AutoRename:     in.AutoRename,
+		Mute:           in.Mute,
+		ClientModified: normalizeTime(in.ClientModified),
+		PropertyGroups: in.PropertyGroups,
+	}
+}
+
+// UploadLargeConcurrent uploads a file of any size as UploadLarge does, but
+// appends up to parallelism 8MB chunks at once with a concurrent upload
+// session, which is faster on high latency links. Each chunk in flight is
+// buffered in memory. On the first failure the other appends are cancelled
+// and its error returned.
+func (c *Files) UploadLargeConcurrent(in *UploadInput, parallelism int) (*UploadOutput, error) {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	r, err := in.reader()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(c.context())
+	defer cancel()
+	files := c.WithContext(ctx)
+
+	session, err := files.UploadSessionStart(&UploadSessionStartInput{
+		SessionType: UploadSessionConcurrent,
+		Reader:      bytes.NewReader(nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	read := func() ([]byte, error) {
+		buf := make([]byte, uploadChunkSize)
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		return buf[:n], err
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var failed error
+	sem := make(chan struct{}, parallelism)
+
+	var offset uint64
+	data, err := read()
+
+	for err == nil && ctx.Err() == nil {
+		// the last append closes the session, so read ahead to find it
+		var next []byte
+		last := len(data) < uploadChunkSize
+		if !last {
+			if next, err = read(); err != nil {
+				break
+			}
+			last = len(next) == 0
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(data []byte, offset uint64, last bool) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			err := files.UploadSessionAppend(&UploadSessionAppendInput{
+				Cursor: UploadSessionCursor{SessionID: session.SessionID, Offset: offset},
+				Close:  last,
+				Reader: bytes.NewReader(data),
+			})
+			if err != nil {
+				once.Do(func() {
+					failed = err
+					cancel()
+				})
+			}
+		}(data, offset, last)
+
+		offset += uint64(len(data))
+		if last {
+			break
+		}
+		data = next
+	}
+
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if failed != nil {
+		return nil, failed
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	out, err := files.UploadSessionFinish(&UploadSessionFinishInput{
+		Cursor: UploadSessionCursor{SessionID: session.SessionID, Offset: offset},
+		Commit: in.commit(),
+	})
+	if err != nil {
+		return nil, in.conflictError(err)
+	}
+
+	return in.output(out.Metadata), nil
+}
+
+// UploadTransform uploads src to path through transform, for example to
+// compress or encrypt it. As the length of the transformed stream is not
+// known it is always uploaded in chunks with an upload session, so it may
+// be of any size.
+func (c *Files) UploadTransform(path string, src io.Reader, transform func(io.Reader) io.Reader, mode WriteMode) (*UploadOutput, error) {
+	out, err := c.uploadStream(transform(src), CommitInfo{Path: path, Mode: mode})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadOutput{Metadata: out.Metadata}, nil
+}
+
+// uploadDedupe uploads to the input path, or the deduplicated path when it
+// exists with different content.
+func (c *Files) uploadDedupe(in *UploadInput) (*UploadOutput, error) {
+	data, err := ioutil.ReadAll(in.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := ContentHash(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok, err := c.uploadNew(*in, in.Path, data, hash)
+	if ok {
+		return out, err
+	}
+
+	out, _, err = c.uploadNew(*in, in.Dedupe(in.Path, hash), data, hash)
+	return out, err
+}
+
+// uploadNew uploads data to path without overwriting or renaming, returning
+// the existing file in its place when it has the same content hash. The
+// returned ok is false when path exists with different content.
+func (c *Files) uploadNew(in UploadInput, path string, data []byte, hash string) (*UploadOutput, bool, error) {
+	in.Path = path
+	in.Mode = WriteModeAdd
+	in.AutoRename = false
+	in.Dedupe = nil
+	in.Reader = bytes.NewReader(data)
+
+	out, err := c.Upload(&in)
+	if !hasTag(err, "path/conflict") {
+		return out, true, err
+	}
+
+	existing, merr := c.GetMetadata(&GetMetadataInput{Path: path})
+	if merr != nil {
+		return nil, true, merr
+	}
+
+	if existing.ContentHash != hash {
+		return nil, false, err
+	}
+
+	return &UploadOutput{Metadata: existing.Metadata}, true, nil
+}
+
+// UploadSessionCursor identifies an upload session and the offset of the
+// next chunk of data, which is the number of bytes uploaded so far.
+type UploadSessionCursor struct {
+	SessionID string `json:"session_id"`
+	Offset    uint64 `json:"offset"`
+}
+
+// CommitInfo describes how the contents of an upload session are committed.
+type CommitInfo struct {
+	Path           string           `json:"path"`
+	Mode           WriteMode        `json:"mode"`
+	AutoRename     bool             `json:"autorename"`
+	Mute           bool             `json:"mute"`
+	ClientModified string           `json:"client_modified,omitempty"`
+	PropertyGroups []*PropertyGroup `json:"property_groups,omitempty"`
+}
+
+// UploadSessionType determines whether the chunks of a session are appended
+// in order, or concurrently at any offset.
+type UploadSessionType string
+
+// Supported upload session types. The chunks of a concurrent session must be
+// a multiple of 4MB, except for the last which closes the session, and it
+// must be started without data.
+const (
+	UploadSessionSequential UploadSessionType = "sequential"
+	UploadSessionConcurrent                   = "concurrent"
+)
+
+// MarshalJSON encodes the session type union.
+func (t UploadSessionType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{".tag": string(t)})
+}
+
+// UnmarshalJSON decodes the session type union.
+func (t *UploadSessionType) UnmarshalJSON(b []byte) error {
+	var v struct {
+		Tag string `json:".tag"`
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	*t = UploadSessionType(v.Tag)
+	return nil
+}
+
+// UploadSessionStartInput request input.
+type UploadSessionStartInput struct {
+	Close       bool              `json:"close"`
+	SessionType UploadSessionType `json:"session_type,omitempty"`
+	Reader      io.Reader         `json:"-"`
+}
+
+// UploadSessionStartOutput request output.
+type UploadSessionStartOutput struct {
+	SessionID string `json:"session_id"`
+}
+
+// UploadSessionStart starts an upload session with the first chunk of data.
+// Set Close when this is the only chunk.
+func (c *Files) UploadSessionStart(in *UploadSessionStartInput) (out *UploadSessionStartOutput, err error) {
+	body, _, err := c.download("/files/upload_session/start", in, in.Reader)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// UploadSessionAppendInput request input.
+type UploadSessionAppendInput struct {
+	Cursor UploadSessionCursor `json:"cursor"`
+	Close  bool                `json:"close"`
+	Reader io.Reader           `json:"-"`
+}
+
+// UploadSessionAppend appends a chunk of data to an upload session. Set Close
+// when this is the last chunk.
+func (c *Files) UploadSessionAppend(in *UploadSessionAppendInput) (err error) {
+	body, _, err := c.download("/files/upload_session/append_v2", in, in.Reader)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	return
+}
+
+// UploadSessionFinishInput request input.
+type UploadSessionFinishInput struct {
+	Cursor UploadSessionCursor `json:"cursor"`
+	Commit CommitInfo          `json:"commit"`
+	Reader io.Reader           `json:"-"`
+}
+
+// UploadSessionFinishOutput request output.
+type UploadSessionFinishOutput struct {
+	Metadata
+}
+
+// UploadSessionFinish finishes an upload session with an optional final chunk
+// of data, and commits the file.
+func (c *Files) UploadSessionFinish(in *UploadSessionFinishInput) (out *UploadSessionFinishOutput, err error) {
+	r := in.Reader
+	if r == nil {
+		r = strings.NewReader("")
+	}
+
+	body, _, err := c.download("/files/upload_session/finish", in, r)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// UploadSessionFinishBatchInput request input. The Reader of each entry is
+// ignored, so every session must already be closed.
+type UploadSessionFinishBatchInput struct {
+	Entries []*UploadSessionFinishInput `json:"entries"`
+}
+
+// UploadSessionFinishError describes why an upload session failed to commit.
+type UploadSessionFinishError struct {
+	Tag  string     `json:".tag"`
+	Path WriteError `json:"path"`
+}
+
+// UploadSessionFinishBatchResultEntry is the result of committing one upload
+// session, where Tag is "success" or "failure".
+type UploadSessionFinishBatchResultEntry struct {
+	Metadata
+	Failure *UploadSessionFinishError `json:"failure,omitempty"`
+}
+
+// UploadSessionFinishBatchOutput request output.
+type UploadSessionFinishBatchOutput struct {
+	Entries []*UploadSessionFinishBatchResultEntry `json:"entries"`
+}
+
+// UploadSessionFinishBatch commits up to 1000 closed upload sessions at once,
+// returning a result for each entry in the same order. Set Mute on each
+// commit to avoid a notification per file.
+func (c *Files) UploadSessionFinishBatch(in *UploadSessionFinishBatchInput) (out *UploadSessionFinishBatchOutput, err error) {
+	body, err := c.call("/files/upload_session/finish_batch_v2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// uploadChunkSize is the size of each chunk appended to an upload session.
+const uploadChunkSize = 8 * 1024 * 1024
+
+// UploadDirOptions are the options used by UploadDir.
+type UploadDirOptions struct {
+	Mode WriteMode
+
+	// Mute commits every file without notifying the user's desktop clients.
+	// Only uploads can be muted: DeleteBatch, CopyBatch and MoveBatch always
+	// notify them.
+	Mute bool
+
+	Concurrency int
+}
+
+// UploadDirError is a local file or directory which failed to upload.
+type UploadDirError struct {
+	LocalPath string
+	Path      string
+	Err       error
+}
+
+// Error string.
+func (e *UploadDirError) Error() string {
+	return fmt.Sprintf("uploading %s to %s: %s", e.LocalPath, e.Path, e.Err)
+}
+
+// UploadDirOutput summarises the files uploaded by UploadDir.
+type UploadDirOutput struct {
+	Uploaded []*Metadata
+	Failed   []*UploadDirError
+}
+
+// uploadDirFile is a local file waiting to be uploaded.
+type uploadDirFile struct {
+	local  string
+	remote string
+	size   int64
+}
+
+// UploadDir uploads the local directory tree at localDir into the Dropbox
+// folder at dropboxDir. Empty directories are created with CreateFolderBatch, files
+// of up to 8MB are committed together with UploadSessionFinishBatch and
+// larger files are uploaded in chunks. Up to opts.Concurrency files (default 4)
+// are uploaded at once. When the client's context is cancelled, see
+// WithContext, uploads in progress are aborted, no further files are started
+// and the context's error is returned along with the summary.
+func (c *Files) UploadDir(localDir, dropboxDir string, opts *UploadDirOptions) (*UploadDirOutput, error) {
+	if opts == nil {
+		opts = &UploadDirOptions{}
+	}
+
+	ctx := c.context()
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	mode := opts.Mode
+	if mode == "" {
+		mode = WriteModeAdd
+	}
+
+	remote := func(local string) string {
+		rel, _ := filepath.Rel(localDir, local)
+		return path.Join("/", dropboxDir, filepath.ToSlash(rel))
+	}
+
+	// uploading a file creates its parent folders, so only folders
+	// without any files beneath them need to be created explicitly
+	var files []*uploadDirFile
+	var dirs []string
+	parents := map[string]bool{}
+
+	err := filepath.Walk(localDir, func(local string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			dirs = append(dirs, local)
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		files = append(files, &uploadDirFile{local, remote(local), info.Size()})
+		for dir := filepath.Dir(local); !parents[dir]; dir = filepath.Dir(dir) {
+			parents[dir] = true
+			if dir == filepath.Dir(dir) {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &UploadDirOutput{}
+
+	var empty []string
+	for _, dir := range dirs {
+		if !parents[dir] && remote(dir) != "/" {
+			empty = append(empty, dir)
+		}
+	}
+
+	if err := c.uploadDirFolders(ctx, empty, remote, out); err != nil {
+		return out, err
+	}
+
+	var mu sync.Mutex
+	var batch []*UploadSessionFinishInput
+	var batched []*uploadDirFile
+
+	fail := func(f *uploadDirFile, err error) {
+		mu.Lock()
+		out.Failed = append(out.Failed, &UploadDirError{f.local, f.remote, err})
+		mu.Unlock()
+	}
+
+	queue := make(chan *uploadDirFile)
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range queue {
+				commit := CommitInfo{Path: f.remote, Mode: mode, Mute: opts.Mute}
+
+				if f.size > uploadChunkSize {
+					m, err := c.uploadDirLarge(f, commit)
+					if err != nil {
+						fail(f, err)
+						continue
+					}
+					mu.Lock()
+					out.Uploaded = append(out.Uploaded, m)
+					mu.Unlock()
+					continue
+				}
+
+				cursor, err := c.uploadDirSmall(f)
+				if err != nil {
+					fail(f, err)
+					continue
+				}
+				mu.Lock()
+				batch = append(batch, &UploadSessionFinishInput{Cursor: *cursor, Commit: commit})
+				batched = append(batched, f)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- f
+	}
+	close(queue)
+	wg.Wait()
+
+	for i := 0; i < len(batch); i += 1000 {
+		j := i + 1000
+		if j > len(batch) {
+			j = len(batch)
+		}
+
+		res, err := c.UploadSessionFinishBatch(&UploadSessionFinishBatchInput{Entries: batch[i:j]})
+		if err != nil {
+			for _, f := range batched[i:j] {
+				fail(f, err)
+			}
+			continue
+		}
+
+		for k, entry := range res.Entries {
+			f := batched[i+k]
+			if entry.Failure != nil {
+				fail(f, fmt.Errorf("%s", entry.Failure.Tag))
+				continue
+			}
+			// the entry is tagged "success" rather than "file"
+			m := entry.Metadata
+			m.Tag = "file"
+			out.Uploaded = append(out.Uploaded, &m)
+		}
+	}
+
+	return out, ctx.Err()
+}
+
+// uploadDirFolders creates the folders for the given local directories,
+// ignoring those which already exist.
+func (c *Files) uploadDirFolders(ctx context.Context, dirs []string, remote func(string) string, out *UploadDirOutput) error {
+	for i := 0; i < len(dirs); i += 10000 {
+		j := i + 10000
+		if j > len(dirs) {
+			j = len(dirs)
+		}
+
+		var paths []string
+		for _, dir := range dirs[i:j] {
+			paths = append(paths, remote(dir))
+		}
+
+		res, err := c.CreateFolderBatch(&CreateFolderBatchInput{Paths: paths})
+		if err != nil {
+			return err
+		}
+
+		entries := res.Entries
+		if res.Async() {
+			entries, err = c.waitCreateFolderBatch(ctx, res.Job(), nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		for k, entry := range entries {
+			if entry.Failure == nil {
+				continue
+			}
+
+			if entry.Failure.Path.Tag == "conflict" && entry.Failure.Path.Conflict.Tag == "folder" {
+				continue
+			}
+
+			local := dirs[i+k]
+			err := fmt.Errorf("%s/%s", entry.Failure.Tag, entry.Failure.Path.Tag)
+			out.Failed = append(out.Failed, &UploadDirError{local, remote(local), err})
+		}
+	}
+
+	return nil
+}
+
+// waitCreateFolderBatch polls an asynchronous CreateFolderBatch job with opts
+// until it completes.
+func (c *Files) waitCreateFolderBatch(ctx context.Context, job *AsyncJobID, opts *PollOptions) ([]*CreateFolderBatchResultEntry, error) {
+	var entries []*CreateFolderBatchResultEntry
+	err := pollJob(ctx, opts, func() (bool, error) {
+		status, err := c.CreateFolderBatchCheckJobStatus(job)
+		if err != nil {
+			return false, err
+		}
+
+		switch status.Tag {
+		case "in_progress":
+			return false, nil
+		case "complete":
+			entries = status.Entries
+			return true, nil
+		default:
+			return false, fmt.Errorf("dropbox: creating folders: %s", status.Tag)
+		}
+	})
+
+	return entries, err
+}
+
+// uploadDirSmall uploads a file in a single closed upload session.
+func (c *Files) uploadDirSmall(f *uploadDirFile) (*UploadSessionCursor, error) {
+	file, err := os.Open(f.local)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	res, err := c.UploadSessionStart(&UploadSessionStartInput{
+		Close:  true,
+		Reader: file,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadSessionCursor{SessionID: res.SessionID, Offset: uint64(f.size)}, nil
+}
+
+// uploadDirLarge uploads a file in chunks and commits it.
+func (c *Files) uploadDirLarge(f *uploadDirFile, commit CommitInfo) (*Metadata, error) {
+	file, err := os.Open(f.local)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	out, err := c.uploadStream(file, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &out.Metadata, nil
+}
+
+// uploadStream uploads r of unknown length in chunks with an upload session.
+func (c *Files) uploadStream(r io.Reader, commit CommitInfo) (*UploadSessionFinishOutput, error) {
+	buf := make([]byte, uploadChunkSize)
+
+	chunk := func() ([]byte, bool, error) {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return buf[:n], true, nil
+		}
+		return buf[:n], false, err
+	}
+
+	data, last, err := chunk()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.UploadSessionStart(&UploadSessionStartInput{
+		Reader: bytes.NewReader(data),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := UploadSessionCursor{SessionID: res.SessionID, Offset: uint64(len(data))}
+	data = nil
+
+	for !last {
+		if data, last, err = chunk(); err != nil {
+			return nil, err
+		}
+
+		if last {
+			break
+		}
+
+		err := c.UploadSessionAppend(&UploadSessionAppendInput{
+			Cursor: cursor,
+			Reader: bytes.NewReader(data),
+		})
+		if err != nil {
+			return nil, err
+		}
+		cursor.Offset += uint64(len(data))
+	}
+
+	return c.UploadSessionFinish(&UploadSessionFinishInput{
+		Cursor: cursor,
+		Commit: commit,
+		Reader: bytes.NewReader(data),
+	})
+}
+
+// DownloadInput request input.
+type DownloadInput struct {
+	Path string `json:"path"`
+
+	// Start and Length request a byte range of the file. A zero Length reads
+	// to the end of the file.
+	Start  int64 `json:"-"`
+	Length int64 `json:"-"`
+}
+
+// header returns the Range header of the input, if any.
+func (in *DownloadInput) header() http.Header {
+	if in.Start == 0 && in.Length == 0 {
+		return nil
+	}
+
+	r := fmt.Sprintf("bytes=%d-", in.Start)
+	if in.Length > 0 {
+		r += strconv.FormatInt(in.Start+in.Length-1, 10)
+	}
+
+	return http.Header{"Range": {r}}
+}
+
+// DownloadOutput request output. Metadata is decoded from the
+// Dropbox-API-Result header, so it describes the revision downloaded.
+type DownloadOutput struct {
+	Body     io.ReadCloser
+	Length   int64
+	Metadata Metadata
+
+	// Total is the size of the whole file, from the Content-Range header of
+	// a range request, otherwise Length.
+	Total int64
+}
+
+// Download a file, or the byte range of it given by Start and Length.
+func (c *Files) Download(in *DownloadInput) (out *DownloadOutput, err error) {
+	in.Path = normalizePath(in.Path)
+
+	res, err := c.contentHeader("/files/download", in, nil, in.header())
+	if err != nil {
+		return
+	}
+
+	out = &DownloadOutput{Body: res.Body, Length: res.ContentLength, Total: res.ContentLength}
+
+	if r := res.Header.Get("Content-Range"); r != "" {
+		if out.Total, err = contentRangeTotal(r); err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+	}
+
+	if result := res.Header.Get("Dropbox-API-Result"); result != "" {
+		if err = json.Unmarshal([]byte(result), &out.Metadata); err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// DownloadZipInput request input.
+type DownloadZipInput struct {
+	Path string `json:"path"`
+}
+
+// DownloadZipOutput request output.
+type DownloadZipOutput struct {
+	Body     io.ReadCloser
+	Length   int64
+	Metadata Metadata
+}
+
+// DownloadZip downloads a folder as a zip archive. The folder must be under
+// 20GB with fewer than 10,000 files and no folder of more than 10,000 entries,
+// otherwise a too_large or too_many_files error is returned.
+func (c *Files) DownloadZip(in *DownloadZipInput) (out *DownloadZipOutput, err error) {
+	var result struct {
+		Metadata Metadata `json:"metadata"`
+	}
+
+	body, l, err := c.downloadResult("/files/download_zip", in, nil, &result)
+	if err != nil {
+		return
+	}
+
+	out = &DownloadZipOutput{body, l, result.Metadata}
+	return
+}
+
+// contentRangeTotal returns the total size of a Content-Range header such as
+// "bytes 0-99/1234", or -1 if it is unknown.
+func contentRangeTotal(r string) (int64, error) {
+	i := strings.LastIndex(r, "/")
+	if i < 0 {
+		return 0, fmt.Errorf("dropbox: invalid Content-Range %q", r)
+	}
+
+	if r[i+1:] == "*" {
+		return -1, nil
+	}
+
+	return strconv.ParseInt(r[i+1:], 10, 64)
+}
+
+// transformReader reads from a transformed body, closing the body on Close.
+type transformReader struct {
+	io.Reader
+	body io.Closer
+}
+
+// Close implementation.
+func (r *transformReader) Close() error {
+	return r.body.Close()
+}
+
+// DownloadTransform downloads the file at path, returning its contents read
+// through transform, for example to decrypt or decompress it. Closing the
+// returned reader closes the underlying download.
+func (c *Files) DownloadTransform(path string, transform func(io.Reader) io.Reader) (io.ReadCloser, error) {
+	out, err := c.Download(&DownloadInput{Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	return &transformReader{transform(out.Body), out.Body}, nil
+}
+
+// DownloadToFile downloads the file at path to localPath, writing it to a
+// temporary file in the same directory which is renamed into place once the
+// content hash has been verified, so localPath is never partially written.
+// A mismatch removes the temporary file and returns ErrContentHashMismatch.
+func (c *Files) DownloadToFile(path, localPath string) (*Metadata, error) {
+	out, err := c.Download(&DownloadInput{Path: path})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	var body io.Reader = out.Body
+	if hash := out.Metadata.ContentHash; hash != "" {
+		body = &hashVerifier{out.Body, newContentHash(), hash}
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeFile(f, body); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	if err := os.Rename(f.Name(), localPath); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return &out.Metadata, nil
+}
+
+// writeFile copies r to f, syncing and closing it.
+func writeFile(f *os.File, r io.Reader) error {
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// SaveURLInput request input.
+type SaveURLInput struct {
+	Path string `json:"path"`
+	URL  string `json:"url"`
+}
+
+// SaveURLOutput request output. Tag is "async_job_id" when the download must
+// be polled with SaveURLCheckJobStatus, or "complete" with the Metadata.
+type SaveURLOutput struct {
+	LaunchResult `json:"-"`
+	Metadata
+}
+
+// UnmarshalJSON implementation, as the LaunchResult and Metadata share the
+// ".tag" key.
+func (o *SaveURLOutput) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &o.LaunchResult); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &o.Metadata)
+}
+
+// SaveURL saves the file at url into Dropbox at path, without it passing
+// through the client.
+func (c *Files) SaveURL(in *SaveURLInput) (out *SaveURLOutput, err error) {
+	body, err := c.call("/files/save_url", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// SaveURLCheckJobStatusInput request input.
+type SaveURLCheckJobStatusInput = AsyncJobID
+
+// SaveURLCheckJobStatusOutput request output. Tag is "in_progress",
+// "complete" with the Metadata, or "failed" with the reason, such as
+// "download_failed" or "not_found".
+type SaveURLCheckJobStatusOutput struct {
+	Tag    string `json:".tag"`
+	Failed *struct {
+		Tag string `json:".tag"`
+	} `json:"failed,omitempty"`
+	Metadata
+}
+
+// SaveURLCheckJobStatus returns the status of an asynchronous SaveURL job.
+func (c *Files) SaveURLCheckJobStatus(in *SaveURLCheckJobStatusInput) (out *SaveURLCheckJobStatusOutput, err error) {
+	body, err := c.call("/files/save_url/check_job_status", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// SaveURLWait saves the file at url as SaveURL does, polling with opts until
+// it completes or the client's context is done.
+func (c *Files) SaveURLWait(in *SaveURLInput, opts *PollOptions) (*Metadata, error) {
+	out, err := c.SaveURL(in)
+	if err != nil {
+		return nil, err
+	}
+
+	switch out.LaunchResult.Tag {
+	case "complete":
+		m := out.Metadata
+		m.Tag = "file"
+		return &m, nil
+	case "async_job_id":
+	default:
+		return nil, fmt.Errorf("dropbox: saving url: %s", out.LaunchResult.Tag)
+	}
+
+	var m *Metadata
+	err = c.PollJob(func() (bool, error) {
+		status, err := c.SaveURLCheckJobStatus(out.Job())
+		if err != nil {
+			return false, err
+		}
+
+		switch status.Tag {
+		case "in_progress":
+			return false, nil
+		case "complete":
+			m = &status.Metadata
+			m.Tag = "file"
+			return true, nil
+		case "failed":
+			if status.Failed != nil {
+				return false, fmt.Errorf("dropbox: saving url: %s", status.Failed.Tag)
+			}
+			fallthrough
+		default:
+			return false, fmt.Errorf("dropbox: saving url: %s", status.Tag)
+		}
+	}, opts)
+
+	return m, err
+}
+
+// GetTemporaryLinkInput request input.
+type GetTemporaryLinkInput struct {
+	Path string `json:"path"`
+}
+
+// GetTemporaryLinkOutput request output.
+type GetTemporaryLinkOutput struct {
+	Metadata Metadata `json:"metadata"`
+	Link     string   `json:"link"`
+}
+
+// GetTemporaryLink returns a link to stream the contents of a file directly
+// from Dropbox. The link expires after four hours, so callers caching it
+// should key it by the Metadata's Rev, as TemporaryLinkCache does.
+func (c *Files) GetTemporaryLink(in *GetTemporaryLinkInput) (out *GetTemporaryLinkOutput, err error) {
+	body, err := c.call("/files/get_temporary_link", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// GetTemporaryUploadLinkInput request input. Duration is the lifetime of
+// the link in seconds, from 60 up to the default of four hours.
+type GetTemporaryUploadLinkInput struct {
+	CommitInfo CommitInfo `json:"commit_info"`
+	Duration   float64    `json:"duration,omitempty"`
+}
+
+// GetTemporaryUploadLinkOutput request output.
+type GetTemporaryUploadLinkOutput struct {
+	Link string `json:"link"`
+}
+
+// GetTemporaryUploadLink returns a one-time link which the file may be
+// uploaded to with a POST of its contents, for example directly from a
+// browser. The upload is committed with the given CommitInfo.
+func (c *Files) GetTemporaryUploadLink(in *GetTemporaryUploadLinkInput) (out *GetTemporaryUploadLinkOutput, err error) {
+	body, err := c.call("/files/get_temporary_upload_link", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
 
 // ThumbnailFormat determines the format of the thumbnail.
 type ThumbnailFormat string
@@ -431,11 +3390,24 @@ const (
 	GetThumbnailSizeW1024H768 = "w1024h768"
 )
 
+// ThumbnailMode determines how the image is resized to the thumbnail size.
+type ThumbnailMode string
+
+const (
+	// GetThumbnailModeStrict scales down the image to fit the size
+	GetThumbnailModeStrict ThumbnailMode = "strict"
+	// GetThumbnailModeBestFit scales down the image to fit the width or height
+	GetThumbnailModeBestFit = "bestfit"
+	// GetThumbnailModeFitOneBestFit scales down the image to fit the shorter side
+	GetThumbnailModeFitOneBestFit = "fitone_bestfit"
+)
+
 // GetThumbnailInput request input.
 type GetThumbnailInput struct {
 	Path   string          `json:"path"`
 	Format ThumbnailFormat `json:"format"`
 	Size   ThumbnailSize   `json:"size"`
+	Mode   ThumbnailMode   `json:"mode,omitempty"`
 }
 
 // GetThumbnailOutput request output.
@@ -446,6 +3418,7 @@ type GetThumbnailOutput struct {
 
 // GetThumbnail a thumbnail for a file. Currently thumbnails are only generated for the
 // files with the following extensions: png, jpeg, png, tiff, tif, gif and bmp.
+// Other files fail with an *Error such as "unsupported_extension".
 func (c *Files) GetThumbnail(in *GetThumbnailInput) (out *GetThumbnailOutput, err error) {
 	body, l, err := c.download("/files/get_thumbnail", in, nil)
 	if err != nil {
@@ -456,27 +3429,374 @@ func (c *Files) GetThumbnail(in *GetThumbnailInput) (out *GetThumbnailOutput, er
 	return
 }
 
+// ThumbnailResource is the file of a GetThumbnailV2, either a Path, which
+// may be an id such as "id:a4ayc_80_OEAAAAAAAAAYa", or a file in a shared
+// Link. The Path of a link to a folder is relative to the folder.
+type ThumbnailResource struct {
+	Path string
+	Link *SharedLink
+}
+
+// MarshalJSON encodes the path_or_link union.
+func (r ThumbnailResource) MarshalJSON() ([]byte, error) {
+	if r.Link == nil {
+		return json.Marshal(map[string]string{
+			".tag": "path",
+			"path": normalizePath(r.Path),
+		})
+	}
+
+	v := map[string]string{
+		".tag": "link",
+		"url":  r.Link.URL,
+	}
+
+	if r.Link.Password != "" {
+		v["password"] = r.Link.Password
+	}
+
+	if r.Path != "" {
+		v["path"] = normalizePath(r.Path)
+	}
+
+	return json.Marshal(v)
+}
+
+// GetThumbnailV2Input request input. Format defaults to JPEG, and Size to
+// 64 by 64 px.
+type GetThumbnailV2Input struct {
+	Resource ThumbnailResource `json:"resource"`
+	Format   ThumbnailFormat   `json:"format,omitempty"`
+	Size     ThumbnailSize     `json:"size,omitempty"`
+	Mode     ThumbnailMode     `json:"mode,omitempty"`
+}
+
+// MinimalFileLinkMetadata is the file of a shared link.
+type MinimalFileLinkMetadata struct {
+	URL  string `json:"url"`
+	ID   string `json:"id,omitempty"`
+	Path string `json:"path,omitempty"`
+	Rev  string `json:"rev"`
+}
+
+// GetThumbnailV2Output request output. FileMetadata is set for a Path, and
+// LinkMetadata for a Link.
+type GetThumbnailV2Output struct {
+	Body   io.ReadCloser `json:"-"`
+	Length int64         `json:"-"`
+
+	FileMetadata *Metadata                `json:"file_metadata,omitempty"`
+	LinkMetadata *MinimalFileLinkMetadata `json:"link_metadata,omitempty"`
+}
+
+// GetThumbnailV2 returns a thumbnail for a file as GetThumbnail does, for a
+// file given by path, id or shared link.
+func (c *Files) GetThumbnailV2(in *GetThumbnailV2Input) (out *GetThumbnailV2Output, err error) {
+	out = &GetThumbnailV2Output{}
+	body, l, err := c.downloadResult("/files/get_thumbnail_v2", in, nil, out)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Body = body
+	out.Length = l
+	return
+}
+
+// thumbnailBatchLimit is the maximum number of entries in a
+// GetThumbnailBatch.
+const thumbnailBatchLimit = 25
+
+// GetThumbnailBatchInput request input.
+type GetThumbnailBatchInput struct {
+	Entries []*GetThumbnailInput `json:"entries"`
+}
+
+// GetThumbnailBatchResultEntry is the thumbnail of one entry, where Tag is
+// "success", or "failure" with a Failure tag such as "unsupported_extension".
+type GetThumbnailBatchResultEntry struct {
+	Tag       string    `json:".tag"`
+	Metadata  *Metadata `json:"metadata,omitempty"`
+	Thumbnail []byte    `json:"thumbnail,omitempty"`
+	Failure   *struct {
+		Tag string `json:".tag"`
+	} `json:"failure,omitempty"`
+}
+
+// GetThumbnailBatchOutput request output, with the Entries in the order
+// requested.
+type GetThumbnailBatchOutput struct {
+	Entries []*GetThumbnailBatchResultEntry `json:"entries"`
+}
+
+// GetThumbnailBatch returns the thumbnails of up to 25 files in one request.
+func (c *Files) GetThumbnailBatch(in *GetThumbnailBatchInput) (out *GetThumbnailBatchOutput, err error) {
+	if len(in.Entries) > thumbnailBatchLimit {
+		return nil, fmt.Errorf("dropbox: at most %d thumbnails may be requested in a batch, got %d", thumbnailBatchLimit, len(in.Entries))
+	}
+
+	body, err := c.contentCall("/files/get_thumbnail_batch", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
 // GetPreviewInput request input.
 type GetPreviewInput struct {
 	Path string `json:"path"`
 }
 
-// GetPreviewOutput request output.
+// GetPreviewOutput request output. ContentType is application/pdf, or
+// text/html for spreadsheets.
 type GetPreviewOutput struct {
-	Body   io.ReadCloser
-	Length int64
+	Body        io.ReadCloser
+	Length      int64
+	ContentType string
 }
 
 // GetPreview a preview for a file. Currently previews are only generated for the
 // files with the following extensions: .doc, .docx, .docm, .ppt, .pps, .ppsx,
 // .ppsm, .pptx, .pptm, .xls, .xlsx, .xlsm, .rtf
 func (c *Files) GetPreview(in *GetPreviewInput) (out *GetPreviewOutput, err error) {
-	body, l, err := c.download("/files/get_preview", in, nil)
+	res, err := c.content("/files/get_preview", in, nil)
+	if err != nil {
+		return
+	}
+
+	out = &GetPreviewOutput{res.Body, res.ContentLength, res.Header.Get("Content-Type")}
+	return
+}
+
+// RevisionResult is the metadata for a revision, or the error retrieving it.
+type RevisionResult struct {
+	Metadata *Metadata
+	Err      error
+}
+
+// RevisionsMetadata returns the metadata of each of the given revisions of the
+// file at path, keyed by rev. Revisions are fetched a few at a time to avoid
+// rate limiting, and a rev belonging to a different file is reported as an
+// error.
+func (c *Files) RevisionsMetadata(path string, revs []string) map[string]*RevisionResult {
+	results := make([]*RevisionResult, len(revs))
+
+	parallel(len(revs), 4, func(i int) {
+		out, err := c.GetMetadata(&GetMetadataInput{Path: "rev:" + revs[i]})
+		if err != nil {
+			results[i] = &RevisionResult{Err: err}
+			return
+		}
+
+		if !strings.HasPrefix(path, "id:") && out.PathLower != strings.ToLower(normalizePath(path)) {
+			err := fmt.Errorf("dropbox: revision %s is of %s, not %s", revs[i], out.PathDisplay, path)
+			results[i] = &RevisionResult{Err: err}
+			return
+		}
+
+		results[i] = &RevisionResult{Metadata: &out.Metadata}
+	})
+
+	m := make(map[string]*RevisionResult, len(revs))
+	for i, rev := range revs {
+		m[rev] = results[i]
+	}
+
+	return m
+}
+
+// MetadataResult is the metadata for a path, or why it could not be
+// retrieved. NotFound is set rather than Err when nothing exists at Path.
+type MetadataResult struct {
+	Path     string
+	Metadata *Metadata
+	NotFound bool
+	Err      error
+}
+
+// GetMetadataMany returns the metadata of each of paths in the same order,
+// fetching up to parallelism at once. Failures are reported per path rather
+// than failing the whole batch. Rate limited requests are retried by the
+// client according to Config.MaxRetries. Once the client's context is done
+// the remaining paths fail with its error without being requested.
+func (c *Files) GetMetadataMany(paths []string, parallelism int) []*MetadataResult {
+	ctx := c.context()
+	results := make([]*MetadataResult, len(paths))
+
+	parallel(len(paths), parallelism, func(i int) {
+		res := &MetadataResult{Path: paths[i]}
+		results[i] = res
+
+		if err := ctx.Err(); err != nil {
+			res.Err = err
+			return
+		}
+
+		out, err := c.GetMetadata(&GetMetadataInput{Path: paths[i]})
+
+		switch {
+		case hasTag(err, "path/not_found"):
+			res.NotFound = true
+		case err != nil:
+			res.Err = err
+		default:
+			res.Metadata = &out.Metadata
+		}
+	})
+
+	return results
+}
+
+// GetMetadataBatch fetches the metadata of paths as GetMetadataMany does,
+// returning the results keyed by path.
+func (c *Files) GetMetadataBatch(paths []string, parallelism int) map[string]*MetadataResult {
+	results := make(map[string]*MetadataResult, len(paths))
+	for _, res := range c.GetMetadataMany(paths, parallelism) {
+		results[res.Path] = res
+	}
+	return results
+}
+
+// LockFileArg is a file to lock, unlock or get the lock of.
+type LockFileArg struct {
+	Path string `json:"path"`
+}
+
+// LockFileBatchInput request input.
+type LockFileBatchInput struct {
+	Entries []*LockFileArg `json:"entries"`
+}
+
+// FileLockContent is the state of a lock, where Tag is "unlocked" or
+// "single_user" with the lock holder.
+type FileLockContent struct {
+	Tag                 string    `json:".tag"`
+	LockHolderAccountID string    `json:"lock_holder_account_id,omitempty"`
+	LockHolderTeamID    string    `json:"lock_holder_team_id,omitempty"`
+	Created             time.Time `json:"created,omitempty"`
+}
+
+// FileLock is the lock on a file.
+type FileLock struct {
+	Content FileLockContent `json:"content"`
+}
+
+// LockFileError describes why an entry of a lock batch failed. When Tag is
+// "lock_conflict" the Lock is the conflicting lock held by another user.
+type LockFileError struct {
+	Tag        string `json:".tag"`
+	PathLookup *struct {
+		Tag string `json:".tag"`
+	} `json:"path_lookup,omitempty"`
+	Lock *FileLock `json:"lock,omitempty"`
+}
+
+// Error string.
+func (e *LockFileError) Error() string {
+	if e.Tag == "lock_conflict" && e.Lock != nil {
+		return fmt.Sprintf("dropbox: file is locked by %s", e.Lock.Content.LockHolderAccountID)
+	}
+
+	if e.PathLookup != nil {
+		return fmt.Sprintf("dropbox: locking file: %s/%s", e.Tag, e.PathLookup.Tag)
+	}
+
+	return fmt.Sprintf("dropbox: locking file: %s", e.Tag)
+}
+
+// LockFileResultEntry is the result for one entry, where Tag is "success"
+// with the file's Metadata and Lock, or "failure".
+type LockFileResultEntry struct {
+	Tag      string         `json:".tag"`
+	Metadata *Metadata      `json:"metadata,omitempty"`
+	Lock     *FileLock      `json:"lock,omitempty"`
+	Failure  *LockFileError `json:"failure,omitempty"`
+}
+
+// LockFileBatchOutput request output, with the Entries in the order
+// requested.
+type LockFileBatchOutput struct {
+	Entries []*LockFileResultEntry `json:"entries"`
+}
+
+// LockFileBatch locks files so that only the current user may edit them.
+func (c *Files) LockFileBatch(in *LockFileBatchInput) (out *LockFileBatchOutput, err error) {
+	return c.lockFileBatch("/files/lock_file_batch", in)
+}
+
+// UnlockFileBatch unlocks files locked by the current user.
+func (c *Files) UnlockFileBatch(in *LockFileBatchInput) (out *LockFileBatchOutput, err error) {
+	return c.lockFileBatch("/files/unlock_file_batch", in)
+}
+
+// GetFileLockBatch returns the locks of files, without changing them.
+func (c *Files) GetFileLockBatch(in *LockFileBatchInput) (out *LockFileBatchOutput, err error) {
+	return c.lockFileBatch("/files/get_file_lock_batch", in)
+}
+
+// lockFileBatch calls one of the lock batch endpoints.
+func (c *Files) lockFileBatch(path string, in *LockFileBatchInput) (out *LockFileBatchOutput, err error) {
+	body, err := c.call(path, in)
 	if err != nil {
 		return
 	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ExportInput request input.
+type ExportInput struct {
+	Path string `json:"path"`
+
+	// ExportFormat is one of the file's ExportInfo.ExportOptions, such as
+	// "docx" or "pdf", defaulting to its ExportAs format.
+	ExportFormat string `json:"export_format,omitempty"`
+}
+
+// ExportInfo describes how a file which is not downloadable can be exported.
+type ExportInfo struct {
+	ExportAs      string   `json:"export_as,omitempty"`
+	ExportOptions []string `json:"export_options,omitempty"`
+}
+
+// ExportMetadata describes an exported file.
+type ExportMetadata struct {
+	Name          string `json:"name"`
+	Size          uint64 `json:"size"`
+	ExportHash    string `json:"export_hash,omitempty"`
+	PaperRevision int64  `json:"paper_revision,omitempty"`
+}
+
+// ExportOutput request output.
+type ExportOutput struct {
+	Body           io.ReadCloser  `json:"-"`
+	Length         int64          `json:"-"`
+	ExportMetadata ExportMetadata `json:"export_metadata"`
+	FileMetadata   Metadata       `json:"file_metadata"`
+}
+
+// Export a file which cannot be downloaded directly, such as a Google Doc.
+// When Dropbox returns an export hash the exported bytes are verified against
+// it, and reading the Body fails with ErrContentHashMismatch if they differ.
+func (c *Files) Export(in *ExportInput) (out *ExportOutput, err error) {
+	out = &ExportOutput{}
+	body, l, err := c.downloadResult("/files/export", in, nil, out)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Body = body
+	out.Length = l
+
+	if hash := out.ExportMetadata.ExportHash; hash != "" {
+		out.Body = &hashVerifier{body, newContentHash(), hash}
+	}
 
-	out = &GetPreviewOutput{body, l}
 	return
 }
 
@@ -488,11 +3808,13 @@ type ListRevisionsInput struct {
 
 // ListRevisionsOutput request output.
 type ListRevisionsOutput struct {
-	IsDeleted bool
-	Entries   []*Metadata
+	IsDeleted     bool        `json:"is_deleted"`
+	ServerDeleted time.Time   `json:"server_deleted,omitempty"`
+	Entries       []*Metadata `json:"entries"`
 }
 
-// ListRevisions gets the revisions of the specified file.
+// ListRevisions gets the revisions of the specified file, newest first. Pass
+// one of their revs to Restore to roll the file back.
 func (c *Files) ListRevisions(in *ListRevisionsInput) (out *ListRevisionsOutput, err error) {
 	body, err := c.call("/files/list_revisions", in)
 	if err != nil {
@@ -500,44 +3822,329 @@ func (c *Files) ListRevisions(in *ListRevisionsInput) (out *ListRevisionsOutput,
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
+	return
+}
+
+// Revision is a file revision along with the change in size from the
+// revision before it.
+type Revision struct {
+	*Metadata
+	Delta int64
+	First bool
+}
+
+// Change returns a human-friendly description of the change in size.
+func (r *Revision) Change() string {
+	switch {
+	case r.First:
+		return fmt.Sprintf("created with %d bytes", r.Size)
+	case r.Delta > 0:
+		return fmt.Sprintf("grew by %d bytes", r.Delta)
+	case r.Delta < 0:
+		return fmt.Sprintf("shrank by %d bytes", -r.Delta)
+	default:
+		return "unchanged in size"
+	}
+}
+
+// RevisionTimeline returns up to the last 100 revisions of the file at path,
+// oldest first, with the change in size between each revision.
+func (c *Files) RevisionTimeline(path string) ([]*Revision, error) {
+	out, err := c.ListRevisions(&ListRevisionsInput{
+		Path:  path,
+		Limit: 100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var revs []*Revision
+	for i := len(out.Entries) - 1; i >= 0; i-- {
+		rev := &Revision{Metadata: out.Entries[i], First: len(revs) == 0}
+		if !rev.First {
+			rev.Delta = int64(rev.Size) - int64(revs[len(revs)-1].Size)
+		}
+		revs = append(revs, rev)
+	}
+
+	return revs, nil
+}
+
+// DiffListings compares two listings by path, returning the entries only in
+// a, those only in b, and the entries of b which changed from a. A file has
+// changed when its content hash differs, or its rev when either lacks a
+// hash, and an entry changes when it becomes a file instead of a folder or
+// vice versa. Deleted entries, as returned by ListFolderContinue, are treated
+// as absent.
+func DiffListings(a, b []*Metadata) (onlyA, onlyB, changed []*Metadata) {
+	return diffListings(a, b, entryChanged)
+}
+
+// diffListings compares two listings as DiffListings does, where changed
+// reports whether an entry at the same path changed.
+func diffListings(a, b []*Metadata, changed func(a, b *Metadata) bool) (onlyA, onlyB, modified []*Metadata) {
+	index := func(entries []*Metadata) map[string]*Metadata {
+		m := make(map[string]*Metadata, len(entries))
+		for _, e := range entries {
+			if e.Tag != "deleted" {
+				m[strings.ToLower(e.PathLower)] = e
+			}
+		}
+		return m
+	}
+
+	inA, inB := index(a), index(b)
+
+	for _, e := range a {
+		if key := strings.ToLower(e.PathLower); e.Tag != "deleted" && inB[key] == nil {
+			onlyA = append(onlyA, e)
+		}
+	}
+
+	for _, e := range b {
+		if e.Tag == "deleted" {
+			continue
+		}
+
+		old := inA[strings.ToLower(e.PathLower)]
+		switch {
+		case old == nil:
+			onlyB = append(onlyB, e)
+		case changed(old, e):
+			modified = append(modified, e)
+		}
+	}
+
 	return
 }
 
-// Normalize path so people can use "/" as they expect.
+// ListingDiff is the difference between two snapshots of a listing.
+type ListingDiff struct {
+	// Added entries are in the new listing but not the old.
+	Added []*Metadata
+
+	// Modified entries are those of the new listing at the same path as an
+	// old entry, but with a different rev or content hash, or which became a
+	// file instead of a folder or vice versa.
+	Modified []*Metadata
+
+	// Removed entries are in the old listing but not the new.
+	Removed []*Metadata
+}
+
+// Diff compares the before and after snapshots of a listing by path. Unlike
+// DiffListings, a file rewritten with the same content is Modified, as its
+// rev changed.
+func Diff(before, after []*Metadata) *ListingDiff {
+	removed, added, modified := diffListings(before, after, revChanged)
+	return &ListingDiff{
+		Added:    added,
+		Modified: modified,
+		Removed:  removed,
+	}
+}
+
+// revChanged returns whether b has a different rev or content hash than a at
+// the same path.
+func revChanged(a, b *Metadata) bool {
+	if a.Tag != b.Tag {
+		return true
+	}
+
+	if a.Tag != "file" {
+		return false
+	}
+
+	return a.Rev != b.Rev || a.ContentHash != b.ContentHash
+}
+
+// entryChanged returns whether b is a changed version of a at the same path.
+func entryChanged(a, b *Metadata) bool {
+	if a.Tag != b.Tag {
+		return true
+	}
+
+	if a.Tag != "file" {
+		return false
+	}
+
+	if a.ContentHash != "" && b.ContentHash != "" {
+		return a.ContentHash != b.ContentHash
+	}
+
+	return a.Rev != b.Rev
+}
+
+// Ancestors returns the parent folders of path from the root down, not
+// including the root itself or path. For example "/a/b/c" returns
+// ["/a", "/a/b"]. Paths which are not absolute, such as "id:" or "rev:"
+// paths, have no known ancestors.
+func Ancestors(p string) []string {
+	if !strings.HasPrefix(p, "/") {
+		return nil
+	}
+
+	var dirs []string
+	for dir := path.Dir(path.Clean(p)); dir != "/"; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+
+	return dirs
+}
+
+// Normalize path so people can use "/" as they expect, collapsing repeated
+// slashes and removing a trailing slash, which Dropbox rejects.
 func normalizePath(s string) string {
-	if s == "/" {
-		return ""
+	if !strings.HasPrefix(s, "/") {
+		return s
 	}
-	return s
+
+	for strings.Contains(s, "//") {
+		s = strings.Replace(s, "//", "/", -1)
+	}
+
+	return strings.TrimSuffix(s, "/")
+}
+
+// InvalidPathError is returned by NormalizePath for a malformed path.
+type InvalidPathError struct {
+	Path string
+}
+
+// Error string.
+func (e *InvalidPathError) Error() string {
+	return fmt.Sprintf("dropbox: path %q must be empty, begin with \"/\", or be an \"id:\", \"rev:\" or \"ns:\" reference", e.Path)
+}
+
+// NormalizePath returns p in the form Dropbox expects, where the root is ""
+// and other paths begin with "/" and have no repeated or trailing slashes.
+// An *InvalidPathError is returned for a relative path. IDs, revisions and
+// namespace-relative paths such as "ns:123/a" are returned unchanged.
+func NormalizePath(p string) (string, error) {
+	for _, prefix := range []string{"id:", "rev:", "ns:"} {
+		if strings.HasPrefix(p, prefix) {
+			return p, nil
+		}
+	}
+
+	if p != "" && !strings.HasPrefix(p, "/") {
+		return "", &InvalidPathError{p}
+	}
+
+	return normalizePath(p), nil
 }
 
 const hashBlockSize = 4 * 1024 * 1024
 
+// contentHash implements hash.Hash for the Dropbox content_hash, which is the
+// SHA-256 of the concatenated SHA-256 digests of each 4MB block.
+type contentHash struct {
+	blocks []byte
+	block  hash.Hash
+	n      int
+}
+
+// newContentHash returns a hash.Hash computing the Dropbox content_hash.
+func newContentHash() hash.Hash {
+	return &contentHash{block: sha256.New()}
+}
+
+// Write implementation.
+func (h *contentHash) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for len(p) > 0 {
+		n := hashBlockSize - h.n
+		if n > len(p) {
+			n = len(p)
+		}
+
+		h.block.Write(p[:n])
+		h.n += n
+		p = p[n:]
+
+		if h.n == hashBlockSize {
+			h.blocks = h.block.Sum(h.blocks)
+			h.block.Reset()
+			h.n = 0
+		}
+	}
+
+	return written, nil
+}
+
+// Sum implementation.
+func (h *contentHash) Sum(b []byte) []byte {
+	blocks := h.blocks
+	if h.n > 0 {
+		blocks = h.block.Sum(blocks[:len(blocks):len(blocks)])
+	}
+
+	sum := sha256.Sum256(blocks)
+	return append(b, sum[:]...)
+}
+
+// Reset implementation.
+func (h *contentHash) Reset() {
+	h.blocks = nil
+	h.block.Reset()
+	h.n = 0
+}
+
+// Size implementation.
+func (h *contentHash) Size() int {
+	return sha256.Size
+}
+
+// BlockSize implementation.
+func (h *contentHash) BlockSize() int {
+	return sha256.BlockSize
+}
+
+// hashVerifier reads from a body, returning ErrContentHashMismatch in place
+// of io.EOF when the content hash of the bytes read does not match.
+type hashVerifier struct {
+	io.ReadCloser
+	hash hash.Hash
+	want string
+}
+
+// Read implementation.
+func (v *hashVerifier) Read(p []byte) (int, error) {
+	n, err := v.ReadCloser.Read(p)
+	v.hash.Write(p[:n])
+
+	if err == io.EOF && fmt.Sprintf("%x", v.hash.Sum(nil)) != v.want {
+		return n, ErrContentHashMismatch
+	}
+
+	return n, err
+}
+
 // ContentHash returns the Dropbox content_hash for a io.Reader.
 // See https://www.dropbox.com/developers/reference/content-hash
 func ContentHash(r io.Reader) (string, error) {
-	buf := make([]byte, hashBlockSize)
-	resultHash := sha256.New()
-	n, err := r.Read(buf)
-	if err != nil && err != io.EOF {
+	h := newContentHash()
+	if _, err := io.Copy(h, r); err != nil {
 		return "", err
 	}
-	if n > 0 {
-		bufHash := sha256.Sum256(buf[:n])
-		resultHash.Write(bufHash[:])
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// RemoteContentHash returns the Dropbox content_hash of the file at path from
+// its metadata, without downloading it, for comparison with ContentHash.
+func (c *Files) RemoteContentHash(path string) (string, error) {
+	out, err := c.GetMetadata(&GetMetadataInput{Path: path})
+	if err != nil {
+		return "", err
 	}
-	for n == hashBlockSize && err == nil {
-		n, err = r.Read(buf)
-		if err != nil && err != io.EOF {
-			return "", err
-		}
-		if n > 0 {
-			bufHash := sha256.Sum256(buf[:n])
-			resultHash.Write(bufHash[:])
-		}
+
+	if out.Tag != "file" {
+		return "", fmt.Errorf("dropbox: %s is a %s, not a file", path, out.Tag)
 	}
-	return fmt.Sprintf("%x", resultHash.Sum(nil)), nil
+
+	return out.ContentHash, nil
 }
 
 // FileContentHash returns the Dropbox content_hash for a local file.
@@ -1,10 +1,23 @@
 package dropbox
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -32,7 +45,7 @@ func TestFiles_Upload(t *testing.T) {
 func TestFiles_Download(t *testing.T) {
 	c := client()
 
-	out, err := c.Files.Download(&DownloadInput{"/Readme.md"})
+	out, err := c.Files.Download(&DownloadInput{Path: "/Readme.md"})
 
 	assert.NoError(t, err, "error downloading")
 	defer out.Body.Close()
@@ -141,7 +154,7 @@ func TestFiles_GetThumbnail(t *testing.T) {
 		})
 		assert.NoError(t, err, "error uploading file")
 	}
-	out, err := c.Files.GetThumbnail(&GetThumbnailInput{"/gray.png", GetThumbnailFormatJPEG, GetThumbnailSizeW32H32})
+	out, err := c.Files.GetThumbnail(&GetThumbnailInput{"/gray.png", GetThumbnailFormatJPEG, GetThumbnailSizeW32H32, ""})
 	assert.NoError(t, err)
 	if err != nil {
 		return
@@ -194,3 +207,2676 @@ func TestFiles_ContentHash(t *testing.T) {
 
 	assert.Equal(t, "485291fa0ee50c016982abbfa943957bcd231aae0492ccbaa22c58e3997b35e0", hash)
 }
+
+func TestFiles_MoveOut(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/move_v2", r.URL.Path)
+
+		var in MoveInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/shared/a.txt", in.FromPath)
+		assert.Equal(t, "/a.txt", in.ToPath)
+		assert.True(t, in.AllowOwnershipTransfer)
+
+		writeJSON(w, 200, map[string]interface{}{"metadata": map[string]string{".tag": "file", "path_lower": "/a.txt"}})
+	})
+
+	out, err := c.Files.MoveOut("/shared/a.txt", "/a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt", out.PathLower)
+}
+
+func TestFiles_MoveOut_error(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, 409, "insufficient_quota/..")
+	})
+
+	_, err := c.Files.MoveOut("/shared/a.txt", "/a.txt")
+	assert.Error(t, err)
+	assert.True(t, IsOwnershipTransferError(err))
+}
+
+func TestFiles_IsShared(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in GetMetadataInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.True(t, in.IncludeHasExplicitSharedMembers)
+
+		switch in.Path {
+		case "/shared/a.txt":
+			writeJSON(w, 200, map[string]interface{}{
+				".tag":         "file",
+				"sharing_info": map[string]interface{}{"read_only": false, "parent_shared_folder_id": "84528192421"},
+			})
+		case "/shared":
+			writeJSON(w, 200, map[string]interface{}{
+				".tag":         "folder",
+				"sharing_info": map[string]interface{}{"read_only": false, "shared_folder_id": "84528192421"},
+			})
+		default:
+			writeJSON(w, 200, map[string]interface{}{".tag": "file"})
+		}
+	})
+
+	shared, id, err := c.Files.IsShared("/shared/a.txt")
+	assert.NoError(t, err)
+	assert.True(t, shared)
+	assert.Equal(t, "84528192421", id)
+
+	shared, id, err = c.Files.IsShared("/shared")
+	assert.NoError(t, err)
+	assert.True(t, shared)
+	assert.Equal(t, "84528192421", id)
+
+	shared, id, err = c.Files.IsShared("/private.txt")
+	assert.NoError(t, err)
+	assert.False(t, shared)
+	assert.Empty(t, id)
+}
+
+func TestFiles_UploadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dropbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	large := bytes.Repeat([]byte("x"), uploadChunkSize*2+100)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "deep", "b.txt"), []byte("bb"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "large.bin"), large, 0644))
+
+	var mu sync.Mutex
+	sessions := map[string][]byte{}
+	committed := map[string][]byte{}
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		data, _ := ioutil.ReadAll(r.Body)
+
+		switch r.URL.Path {
+		case "/2/files/create_folder_batch":
+			var in CreateFolderBatchInput
+			assert.NoError(t, json.Unmarshal(data, &in))
+			assert.Equal(t, []string{"/backup/empty"}, in.Paths)
+			writeJSON(w, 200, map[string]interface{}{
+				".tag":    "complete",
+				"entries": []interface{}{map[string]interface{}{".tag": "success", "metadata": map[string]string{"path_lower": "/backup/empty"}}},
+			})
+		case "/2/files/upload_session/start":
+			id := fmt.Sprintf("session%d", len(sessions))
+			sessions[id] = data
+			writeJSON(w, 200, map[string]string{"session_id": id})
+		case "/2/files/upload_session/append_v2":
+			var in UploadSessionAppendInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, uint64(len(sessions[in.Cursor.SessionID])), in.Cursor.Offset)
+			sessions[in.Cursor.SessionID] = append(sessions[in.Cursor.SessionID], data...)
+			writeJSON(w, 200, nil)
+		case "/2/files/upload_session/finish":
+			var in UploadSessionFinishInput
+			assert.NoError(t, decodeArg(r, &in))
+			committed[in.Commit.Path] = append(sessions[in.Cursor.SessionID], data...)
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": in.Commit.Path})
+		case "/2/files/upload_session/finish_batch_v2":
+			var in UploadSessionFinishBatchInput
+			assert.NoError(t, json.Unmarshal(data, &in))
+			var entries []interface{}
+			for _, e := range in.Entries {
+				assert.Equal(t, uint64(len(sessions[e.Cursor.SessionID])), e.Cursor.Offset)
+				committed[e.Commit.Path] = sessions[e.Cursor.SessionID]
+				entries = append(entries, map[string]string{".tag": "success", "path_lower": e.Commit.Path})
+			}
+			writeJSON(w, 200, map[string]interface{}{"entries": entries})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	out, err := c.Files.UploadDir(dir, "/backup", &UploadDirOptions{Concurrency: 2})
+	assert.NoError(t, err)
+	assert.Empty(t, out.Failed)
+	assert.Len(t, out.Uploaded, 3)
+
+	assert.Equal(t, []byte("a"), committed["/backup/a.txt"])
+	assert.Equal(t, []byte("bb"), committed["/backup/sub/deep/b.txt"])
+	assert.Equal(t, large, committed["/backup/sub/large.bin"])
+}
+
+func TestFiles_UploadDir_cancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dropbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out, err := c.Files.WithContext(ctx).UploadDir(dir, "/backup", nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, out.Uploaded)
+}
+
+func TestFiles_UploadDir_cancelInFlight(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dropbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/upload_session/start", r.URL.Path)
+		cancel()
+		<-r.Context().Done()
+	})
+
+	out, err := c.Files.WithContext(ctx).UploadDir(dir, "/backup", nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, out.Uploaded)
+	assert.Len(t, out.Failed, 1)
+	assert.ErrorIs(t, out.Failed[0].Err, context.Canceled)
+}
+
+func TestFiles_RevisionTimeline(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/list_revisions", r.URL.Path)
+
+		writeJSON(w, 200, map[string]interface{}{
+			"is_deleted": false,
+			"entries": []map[string]interface{}{
+				{"rev": "c", "size": 120},
+				{"rev": "b", "size": 150},
+				{"rev": "a", "size": 100},
+			},
+		})
+	})
+
+	revs, err := c.Files.RevisionTimeline("/doc.txt")
+	assert.NoError(t, err)
+	assert.Len(t, revs, 3)
+
+	assert.Equal(t, "a", revs[0].Rev)
+	assert.True(t, revs[0].First)
+	assert.Equal(t, "created with 100 bytes", revs[0].Change())
+
+	assert.Equal(t, "b", revs[1].Rev)
+	assert.Equal(t, int64(50), revs[1].Delta)
+	assert.Equal(t, "grew by 50 bytes", revs[1].Change())
+
+	assert.Equal(t, "c", revs[2].Rev)
+	assert.Equal(t, int64(-30), revs[2].Delta)
+	assert.Equal(t, "shrank by 30 bytes", revs[2].Change())
+}
+
+func TestFiles_Export(t *testing.T) {
+	data := []byte("# Exported\n")
+	hash, err := ContentHash(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	for _, exportHash := range []string{hash, "0000"} {
+		c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/2/files/export", r.URL.Path)
+
+			result, _ := json.Marshal(map[string]interface{}{
+				"export_metadata": map[string]interface{}{"name": "doc.md", "size": len(data), "export_hash": exportHash},
+				"file_metadata":   map[string]interface{}{"name": "doc.gdoc"},
+			})
+			w.Header().Set("Dropbox-API-Result", string(result))
+			w.Write(data)
+		})
+
+		out, err := c.Files.Export(&ExportInput{Path: "/doc.gdoc"})
+		assert.NoError(t, err)
+		assert.Equal(t, "doc.md", out.ExportMetadata.Name)
+		assert.Equal(t, "doc.gdoc", out.FileMetadata.Name)
+
+		body, err := ioutil.ReadAll(out.Body)
+		out.Body.Close()
+
+		if exportHash == hash {
+			assert.NoError(t, err)
+			assert.Equal(t, data, body)
+		} else {
+			assert.Equal(t, ErrContentHashMismatch, err)
+		}
+	}
+}
+
+func TestFiles_Upload_dedupe(t *testing.T) {
+	files := map[string][]byte{}
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/upload":
+			var in UploadInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, WriteModeAdd, in.Mode)
+			assert.False(t, in.AutoRename)
+
+			if _, ok := files[in.Path]; ok {
+				writeError(w, 409, "path/conflict/file/..")
+				return
+			}
+
+			files[in.Path], _ = ioutil.ReadAll(r.Body)
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_display": in.Path})
+		case "/2/files/get_metadata":
+			var in GetMetadataInput
+			assert.NoError(t, decodeArg(r, &in))
+			hash, _ := ContentHash(bytes.NewReader(files[in.Path]))
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_display": in.Path, "content_hash": hash})
+		}
+	})
+
+	upload := func(data string) *UploadOutput {
+		out, err := c.Files.Upload(&UploadInput{
+			Path:   "/notes.txt",
+			Mode:   WriteModeAdd,
+			Reader: bytes.NewBufferString(data),
+			Dedupe: ContentHashSuffix,
+		})
+		assert.NoError(t, err)
+		return out
+	}
+
+	assert.Equal(t, "/notes.txt", upload("hello").PathDisplay)
+	assert.Equal(t, "/notes.txt", upload("hello").PathDisplay)
+	assert.Len(t, files, 1)
+
+	hash, _ := ContentHash(bytes.NewBufferString("world"))
+	renamed := "/notes (" + hash[:8] + ").txt"
+	assert.Equal(t, renamed, upload("world").PathDisplay)
+	assert.Equal(t, renamed, upload("world").PathDisplay)
+	assert.Len(t, files, 2)
+}
+
+func TestFiles_RevisionsMetadata(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in GetMetadataInput
+		assert.NoError(t, decodeArg(r, &in))
+
+		switch in.Path {
+		case "rev:a1":
+			writeJSON(w, 200, map[string]interface{}{".tag": "file", "rev": "a1", "size": 10, "path_lower": "/doc.txt"})
+		case "rev:a2":
+			writeJSON(w, 200, map[string]interface{}{".tag": "file", "rev": "a2", "size": 20, "path_lower": "/doc.txt"})
+		case "rev:b1":
+			writeJSON(w, 200, map[string]interface{}{".tag": "file", "rev": "b1", "path_lower": "/other.txt"})
+		default:
+			writeError(w, 409, "path/not_found/..")
+		}
+	})
+
+	res := c.Files.RevisionsMetadata("/Doc.txt", []string{"a1", "a2", "b1", "zz"})
+	assert.Len(t, res, 4)
+
+	assert.NoError(t, res["a1"].Err)
+	assert.Equal(t, uint64(10), res["a1"].Metadata.Size)
+
+	assert.NoError(t, res["a2"].Err)
+	assert.Equal(t, uint64(20), res["a2"].Metadata.Size)
+
+	assert.Error(t, res["b1"].Err)
+	assert.Nil(t, res["b1"].Metadata)
+
+	assert.Error(t, res["zz"].Err)
+	assert.Equal(t, "path/not_found/..", res["zz"].Err.Error())
+
+	res = c.Files.RevisionsMetadata("//Doc.txt/", []string{"a1"})
+	assert.NoError(t, res["a1"].Err, "path is normalized")
+}
+
+func TestFiles_DownloadSearchResultsZip(t *testing.T) {
+	contents := map[string]string{
+		"/docs/a.txt":     "alpha",
+		"/docs/sub/b.txt": "bravo",
+	}
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/search_v2":
+			var in SearchV2Input
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "txt", in.Query)
+			assert.Equal(t, "/docs", in.Options.Path)
+			assert.True(t, in.Options.FilenameOnly)
+
+			writeJSON(w, 200, map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{"metadata": map[string]interface{}{".tag": "metadata", "metadata": map[string]string{".tag": "folder", "path_lower": "/docs/sub", "path_display": "/docs/sub"}}},
+					map[string]interface{}{"metadata": map[string]interface{}{".tag": "metadata", "metadata": map[string]string{".tag": "file", "path_lower": "/docs/a.txt", "path_display": "/docs/a.txt"}}},
+				},
+				"has_more": true,
+				"cursor":   "c1",
+			})
+		case "/2/files/search/continue_v2":
+			var in SearchContinueInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "c1", in.Cursor)
+
+			writeJSON(w, 200, map[string]interface{}{
+				"matches": []interface{}{
+					map[string]interface{}{"metadata": map[string]interface{}{".tag": "metadata", "metadata": map[string]string{".tag": "file", "path_lower": "/docs/sub/b.txt", "path_display": "/docs/sub/b.txt"}}},
+				},
+				"has_more": false,
+			})
+		case "/2/files/download":
+			var in DownloadInput
+			assert.NoError(t, decodeArg(r, &in))
+			w.Write([]byte(contents[in.Path]))
+		}
+	})
+
+	var buf bytes.Buffer
+	n, err := c.Files.DownloadSearchResultsZip(&SearchInput{Path: "/docs", Query: "txt"}, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, z.File, 2)
+
+	for _, f := range z.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		b, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, contents["/"+f.Name], string(b))
+	}
+}
+
+func TestFiles_WaitVisible(t *testing.T) {
+	lists := 0
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in ListFolderInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/photos", in.Path)
+
+		lists++
+		entries := []map[string]string{{".tag": "file", "id": "id:other"}}
+		if lists > 2 {
+			entries = append(entries, map[string]string{".tag": "file", "id": "id:new"})
+		}
+
+		writeJSON(w, 200, map[string]interface{}{"entries": entries})
+	})
+
+	err := c.Files.WaitVisible(&Metadata{ID: "id:new", PathLower: "/photos/new.jpg"}, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, lists)
+
+	err = c.Files.WaitVisible(&Metadata{ID: "id:missing", PathLower: "/photos/missing.jpg"}, 200*time.Millisecond)
+	assert.Equal(t, ErrNotVisible, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = c.WithContext(ctx).Files.WaitVisible(&Metadata{ID: "id:missing", PathLower: "/photos/missing.jpg"}, time.Minute)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < 5*time.Second, "polling stops with the context")
+}
+
+func TestFiles_UploadConflictOf(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/upload":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(409)
+			w.Write([]byte(`{
+				"error_summary": "path/conflict/file/...",
+				"error": {".tag": "path", "reason": {".tag": "conflict", "conflict": {".tag": "file"}}, "upload_session_id": "AAAAAAAAAF8pZ3rnG4tJ0A"}
+			}`))
+		case "/2/files/get_metadata":
+			writeJSON(w, 200, map[string]string{".tag": "file", "rev": "a1c10ce0dd78"})
+		}
+	})
+
+	_, err := c.Files.Upload(&UploadInput{
+		Path:   "/doc.txt",
+		Mode:   WriteModeOverwrite,
+		Reader: bytes.NewBufferString("hello"),
+	})
+	assert.Error(t, err)
+
+	conflict, err := c.Files.UploadConflictOf(err, "/doc.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "file", conflict.Conflict)
+	assert.Equal(t, "a1c10ce0dd78", conflict.Rev)
+	assert.Equal(t, "AAAAAAAAAF8pZ3rnG4tJ0A", conflict.UploadSessionID)
+
+	conflict, err = c.Files.UploadConflictOf(&Error{Summary: "path/insufficient_space/.."}, "/doc.txt")
+	assert.NoError(t, err)
+	assert.Nil(t, conflict)
+}
+
+func TestFiles_UploadDir_mute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dropbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644))
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/upload_session/start":
+			writeJSON(w, 200, map[string]string{"session_id": "session"})
+		case "/2/files/upload_session/finish_batch_v2":
+			var in UploadSessionFinishBatchInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Len(t, in.Entries, 2)
+
+			var entries []interface{}
+			for _, e := range in.Entries {
+				assert.True(t, e.Commit.Mute)
+				entries = append(entries, map[string]string{".tag": "success"})
+			}
+			writeJSON(w, 200, map[string]interface{}{"entries": entries})
+		}
+	})
+
+	out, err := c.Files.UploadDir(dir, "/", &UploadDirOptions{Mute: true})
+	assert.NoError(t, err)
+	assert.Len(t, out.Uploaded, 2)
+}
+
+func TestAncestors(t *testing.T) {
+	assert.Empty(t, Ancestors(""))
+	assert.Empty(t, Ancestors("/"))
+	assert.Empty(t, Ancestors("/a"))
+	assert.Empty(t, Ancestors("id:a4ayc_80_OEAAAAAAAAAYa"))
+	assert.Equal(t, []string{"/a"}, Ancestors("/a/b"))
+	assert.Equal(t, []string{"/a", "/a/b"}, Ancestors("/a/b/c"))
+	assert.Equal(t, []string{"/a", "/a/b"}, Ancestors("/a//b/c/"))
+}
+
+func TestFiles_Watch(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/list_folder/longpoll":
+			assert.Equal(t, "notify.dropboxapi.com", r.URL.Host)
+			assert.Empty(t, r.Header.Get("Authorization"))
+
+			mu.Lock()
+			polls++
+			n := polls
+			mu.Unlock()
+
+			if n == 1 {
+				writeJSON(w, 200, map[string]interface{}{"changes": true})
+				return
+			}
+
+			<-r.Context().Done()
+		case "/2/files/list_folder/continue":
+			var in ListFolderContinueInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			if in.Cursor == "cursor1" {
+				writeJSON(w, 200, map[string]interface{}{
+					"entries":  []map[string]string{{".tag": "file", "path_lower": "/a.txt"}},
+					"cursor":   "cursor2",
+					"has_more": true,
+				})
+				return
+			}
+
+			assert.Equal(t, "cursor2", in.Cursor)
+			writeJSON(w, 200, map[string]interface{}{
+				"entries":  []map[string]string{{".tag": "deleted", "path_lower": "/b.txt"}},
+				"cursor":   "cursor3",
+				"has_more": false,
+			})
+		}
+	})
+
+	changes, stop := c.Files.Watch("cursor1")
+
+	batch := <-changes
+	assert.Len(t, batch, 1)
+	assert.Equal(t, "/a.txt", batch[0].PathLower)
+
+	batch = <-changes
+	assert.Len(t, batch, 1)
+	assert.Equal(t, "deleted", batch[0].Tag)
+
+	stop()
+
+	_, ok := <-changes
+	assert.False(t, ok, "channel should be closed")
+}
+
+func TestFiles_CopyToMany(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/copy_batch_v2", r.URL.Path)
+
+		var in CopyBatchInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Len(t, in.Entries, 3)
+
+		var entries []interface{}
+		for _, e := range in.Entries {
+			assert.Equal(t, "/template.txt", e.FromPath)
+
+			if e.ToPath == "/c/template.txt" {
+				entries = append(entries, map[string]interface{}{
+					".tag":    "failure",
+					"failure": map[string]interface{}{".tag": "relocation_error", "relocation_error": map[string]string{".tag": "to"}},
+				})
+				continue
+			}
+
+			entries = append(entries, map[string]interface{}{
+				".tag":    "success",
+				"success": map[string]string{".tag": "file", "path_lower": e.ToPath},
+			})
+		}
+
+		writeJSON(w, 200, map[string]interface{}{".tag": "complete", "entries": entries})
+	})
+
+	res, err := c.Files.CopyToMany("/template.txt", []string{"/a/template.txt", "/b/template.txt", "/c/template.txt"})
+	assert.NoError(t, err)
+	assert.Len(t, res, 3)
+
+	assert.Equal(t, "success", res[0].Tag)
+	assert.Equal(t, "/a/template.txt", res[0].Success.PathLower)
+	assert.Equal(t, "success", res[1].Tag)
+	assert.Equal(t, "/b/template.txt", res[1].Success.PathLower)
+	assert.Equal(t, "failure", res[2].Tag)
+	assert.Equal(t, "to", res[2].Failure.RelocationError.Tag)
+}
+
+func TestFiles_CopyToMany_batches(t *testing.T) {
+	var sizes []int
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in CopyBatchInput
+		assert.NoError(t, decodeArg(r, &in))
+		sizes = append(sizes, len(in.Entries))
+
+		var entries []interface{}
+		for _, e := range in.Entries {
+			entries = append(entries, map[string]interface{}{
+				".tag":    "success",
+				"success": map[string]string{".tag": "file", "path_lower": e.ToPath},
+			})
+		}
+		writeJSON(w, 200, map[string]interface{}{".tag": "complete", "entries": entries})
+	})
+
+	var dests []string
+	for i := 0; i < 1500; i++ {
+		dests = append(dests, fmt.Sprintf("/%d.txt", i))
+	}
+
+	res, err := c.Files.CopyToMany("/template.txt", dests)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1000, 500}, sizes)
+	assert.Len(t, res, 1500)
+	assert.Equal(t, "/1499.txt", res[1499].Success.PathLower)
+}
+
+func TestFiles_CopyToMany_cancel(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/copy_batch_v2":
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+		default:
+			writeJSON(w, 200, map[string]string{".tag": "in_progress"})
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Files.WithContext(ctx).CopyToMany("/template.txt", []string{"/a.txt"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestFiles_SearchV2_fileCategories(t *testing.T) {
+	files := []map[string]string{
+		{".tag": "file", "name": "report.pdf", "path_lower": "/report.pdf"},
+		{".tag": "file", "name": "report.docx", "path_lower": "/report.docx"},
+		{".tag": "file", "name": "report.png", "path_lower": "/report.png"},
+	}
+
+	categories := map[string]FileCategory{
+		"report.pdf":  FileCategoryPDF,
+		"report.docx": FileCategoryDocument,
+		"report.png":  FileCategoryImage,
+	}
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/search_v2", r.URL.Path)
+
+		var in SearchV2Input
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "report", in.Query)
+
+		var matches []interface{}
+		for _, f := range files {
+			ok := in.Options == nil || len(in.Options.FileCategories) == 0
+			if in.Options != nil {
+				for _, c := range in.Options.FileCategories {
+					ok = ok || c == categories[f["name"]]
+				}
+			}
+
+			if ok {
+				matches = append(matches, map[string]interface{}{
+					"metadata": map[string]interface{}{".tag": "metadata", "metadata": f},
+				})
+			}
+		}
+
+		writeJSON(w, 200, map[string]interface{}{"matches": matches, "has_more": false})
+	})
+
+	out, err := c.Files.SearchV2(&SearchV2Input{Query: "report"})
+	assert.NoError(t, err)
+	assert.Len(t, out.Matches, 3)
+
+	out, err = c.Files.SearchV2(&SearchV2Input{
+		Query: "report",
+		Options: &SearchOptions{
+			FileCategories: []FileCategory{FileCategoryPDF},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, out.Matches, 1)
+	assert.Equal(t, "/report.pdf", out.Matches[0].Metadata.Metadata.PathLower)
+}
+
+func TestFiles_RemoteContentHash(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in GetMetadataInput
+		assert.NoError(t, decodeArg(r, &in))
+
+		if in.Path == "/photos" {
+			writeJSON(w, 200, map[string]string{".tag": "folder"})
+			return
+		}
+
+		writeJSON(w, 200, map[string]string{
+			".tag":         "file",
+			"content_hash": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		})
+	})
+
+	hash, err := c.Files.RemoteContentHash("/empty.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hash)
+
+	_, err = c.Files.RemoteContentHash("/photos")
+	assert.Error(t, err)
+}
+
+func TestDiffListings(t *testing.T) {
+	a := []*Metadata{
+		{Tag: "folder", PathLower: "/docs"},
+		{Tag: "file", PathLower: "/docs/same.txt", Rev: "1", ContentHash: "aaa"},
+		{Tag: "file", PathLower: "/docs/edited.txt", Rev: "1", ContentHash: "bbb"},
+		{Tag: "file", PathLower: "/docs/touched.txt", Rev: "1", ContentHash: "ccc"},
+		{Tag: "file", PathLower: "/docs/nohash.txt", Rev: "1"},
+		{Tag: "file", PathLower: "/docs/removed.txt", Rev: "1"},
+		{Tag: "folder", PathLower: "/docs/became-file"},
+	}
+
+	b := []*Metadata{
+		{Tag: "folder", PathLower: "/docs"},
+		{Tag: "file", PathLower: "/docs/same.txt", Rev: "1", ContentHash: "aaa"},
+		{Tag: "file", PathLower: "/docs/edited.txt", Rev: "2", ContentHash: "bbb2"},
+		{Tag: "file", PathLower: "/docs/touched.txt", Rev: "2", ContentHash: "ccc"},
+		{Tag: "file", PathLower: "/docs/nohash.txt", Rev: "2"},
+		{Tag: "deleted", PathLower: "/docs/removed.txt"},
+		{Tag: "file", PathLower: "/docs/became-file", Rev: "1"},
+		{Tag: "file", PathLower: "/docs/added.txt", Rev: "1"},
+	}
+
+	onlyA, onlyB, changed := DiffListings(a, b)
+
+	paths := func(entries []*Metadata) (s []string) {
+		for _, e := range entries {
+			s = append(s, e.PathLower)
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"/docs/removed.txt"}, paths(onlyA))
+	assert.Equal(t, []string{"/docs/added.txt"}, paths(onlyB))
+	assert.Equal(t, []string{"/docs/edited.txt", "/docs/nohash.txt", "/docs/became-file"}, paths(changed))
+}
+
+func TestDiff(t *testing.T) {
+	before := []*Metadata{
+		{Tag: "folder", PathLower: "/docs"},
+		{Tag: "file", PathLower: "/docs/same.txt", Rev: "1", ContentHash: "aaa"},
+		{Tag: "file", PathLower: "/docs/edited.txt", Rev: "1", ContentHash: "bbb"},
+		{Tag: "file", PathLower: "/docs/rewritten.txt", Rev: "1", ContentHash: "ccc"},
+		{Tag: "file", PathLower: "/docs/removed.txt", Rev: "1"},
+		{Tag: "folder", PathLower: "/docs/became-file"},
+	}
+
+	after := []*Metadata{
+		{Tag: "folder", PathLower: "/docs"},
+		{Tag: "file", PathLower: "/docs/same.txt", Rev: "1", ContentHash: "aaa"},
+		{Tag: "file", PathLower: "/docs/edited.txt", Rev: "2", ContentHash: "bbb2"},
+		{Tag: "file", PathLower: "/docs/rewritten.txt", Rev: "2", ContentHash: "ccc"},
+		{Tag: "deleted", PathLower: "/docs/removed.txt"},
+		{Tag: "file", PathLower: "/docs/became-file", Rev: "1"},
+		{Tag: "file", PathLower: "/docs/added.txt", Rev: "1"},
+	}
+
+	paths := func(entries []*Metadata) (s []string) {
+		for _, e := range entries {
+			s = append(s, e.PathLower)
+		}
+		return
+	}
+
+	diff := Diff(before, after)
+	assert.Equal(t, []string{"/docs/added.txt"}, paths(diff.Added))
+	assert.Equal(t, []string{"/docs/edited.txt", "/docs/rewritten.txt", "/docs/became-file"}, paths(diff.Modified))
+	assert.Equal(t, []string{"/docs/removed.txt"}, paths(diff.Removed))
+}
+
+func TestFiles_Upload_createParents(t *testing.T) {
+	created := false
+	uploads := 0
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/upload":
+			uploads++
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.Equal(t, "hello", string(body))
+
+			if !created {
+				writeError(w, 409, "path/not_found/..")
+				return
+			}
+
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a/b/c.txt"})
+		case "/2/files/create_folder":
+			var in CreateFolderInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "/a/b", in.Path)
+			created = true
+			writeJSON(w, 200, map[string]string{"path_lower": "/a/b"})
+		}
+	})
+
+	out, err := c.Files.Upload(&UploadInput{
+		Path:          "/a/b/c.txt",
+		Reader:        bytes.NewBufferString("hello"),
+		CreateParents: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/a/b/c.txt", out.PathLower)
+	assert.True(t, created)
+	assert.Equal(t, 2, uploads)
+}
+
+func TestFiles_MkdirAll_exists(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, 409, "path/conflict/folder/..")
+	})
+
+	assert.NoError(t, c.Files.MkdirAll("/a/b"))
+}
+
+func TestFiles_CreateFolderIfNotExists(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in CreateFolderInput
+		assert.NoError(t, decodeArg(r, &in))
+
+		switch {
+		case r.URL.Path == "/2/files/create_folder" && in.Path == "/new":
+			writeJSON(w, 200, map[string]string{"name": "new", "path_lower": "/new", "id": "id:new"})
+		case r.URL.Path == "/2/files/create_folder" && in.Path == "/file.txt":
+			writeError(w, 409, "path/conflict/file/..")
+		case r.URL.Path == "/2/files/create_folder":
+			writeError(w, 409, "path/conflict/folder/..")
+		case r.URL.Path == "/2/files/get_metadata":
+			writeJSON(w, 200, map[string]string{".tag": "folder", "name": "Existing", "path_lower": "/existing", "id": "id:existing"})
+		}
+	})
+
+	out, err := c.Files.CreateFolderIfNotExists(&CreateFolderInput{Path: "/new"})
+	assert.NoError(t, err)
+	assert.Equal(t, "id:new", out.ID)
+
+	out, err = c.Files.CreateFolderIfNotExists(&CreateFolderInput{Path: "/Existing"})
+	assert.NoError(t, err)
+	assert.Equal(t, &CreateFolderOutput{Name: "Existing", PathLower: "/existing", ID: "id:existing"}, out)
+
+	_, err = c.Files.CreateFolderIfNotExists(&CreateFolderInput{Path: "/file.txt"})
+	assert.True(t, IsConflict(err))
+}
+
+// closeRecorder records whether the body was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestFiles_DownloadTransform(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("hello world"))
+	zw.Close()
+
+	var body *closeRecorder
+	c := New(NewConfig("token"))
+	c.HTTPClient = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			body = &closeRecorder{Reader: bytes.NewReader(compressed.Bytes())}
+			return &http.Response{StatusCode: 200, Body: body, Header: http.Header{}}, nil
+		}),
+	}
+
+	r, err := c.Files.DownloadTransform("/a.txt.gz", func(r io.Reader) io.Reader { return r })
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, compressed.Bytes(), data)
+	assert.NoError(t, r.Close())
+	assert.True(t, body.closed)
+
+	r, err = c.Files.DownloadTransform("/a.txt.gz", func(r io.Reader) io.Reader {
+		zr, err := gzip.NewReader(r)
+		assert.NoError(t, err)
+		return zr
+	})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.NoError(t, r.Close())
+	assert.True(t, body.closed)
+}
+
+func TestFiles_UploadTransform(t *testing.T) {
+	var stored []byte
+	var session []byte
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var data []byte
+		if r.Body != nil {
+			data, _ = ioutil.ReadAll(r.Body)
+		}
+
+		switch r.URL.Path {
+		case "/2/files/upload_session/start":
+			session = data
+			writeJSON(w, 200, map[string]string{"session_id": "session"})
+		case "/2/files/upload_session/append_v2":
+			session = append(session, data...)
+			writeJSON(w, 200, nil)
+		case "/2/files/upload_session/finish":
+			var in UploadSessionFinishInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, uint64(len(session)), in.Cursor.Offset)
+			assert.Equal(t, WriteMode(WriteModeOverwrite), in.Commit.Mode)
+			stored = append(session, data...)
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": in.Commit.Path})
+		case "/2/files/download":
+			w.Write(stored)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	compress := func(r io.Reader) io.Reader {
+		pr, pw := io.Pipe()
+		go func() {
+			zw := gzip.NewWriter(pw)
+			_, err := io.Copy(zw, r)
+			if err == nil {
+				err = zw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr
+	}
+
+	out, err := c.Files.UploadTransform("/a.txt.gz", bytes.NewReader([]byte("hello world")), compress, WriteModeOverwrite)
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt.gz", out.PathLower)
+
+	r, err := c.Files.DownloadTransform("/a.txt.gz", func(r io.Reader) io.Reader {
+		zr, err := gzip.NewReader(r)
+		assert.NoError(t, err)
+		return zr
+	})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	assert.NoError(t, r.Close())
+}
+
+func TestFiles_WatchFolder_reset(t *testing.T) {
+	var mu sync.Mutex
+	lists := 0
+	polls := 0
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/2/files/list_folder":
+			var in ListFolderInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "/photos", in.Path)
+			assert.True(t, in.Recursive)
+
+			lists++
+			writeJSON(w, 200, map[string]interface{}{
+				"entries": []map[string]string{{".tag": "file", "path_lower": fmt.Sprintf("/photos/%d.jpg", lists)}},
+				"cursor":  fmt.Sprintf("list%d", lists),
+			})
+		case "/2/files/list_folder/longpoll":
+			polls++
+			if polls <= 2 {
+				writeJSON(w, 200, map[string]interface{}{"changes": true})
+				return
+			}
+			mu.Unlock()
+			<-r.Context().Done()
+			mu.Lock()
+		case "/2/files/list_folder/continue":
+			var in ListFolderContinueInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			if in.Cursor == "list1" {
+				writeError(w, 409, "reset/..")
+				return
+			}
+
+			assert.Equal(t, "list2", in.Cursor)
+			writeJSON(w, 200, map[string]interface{}{
+				"entries": []map[string]string{{".tag": "deleted", "path_lower": "/photos/2.jpg"}},
+				"cursor":  "list3",
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	changes, stop := c.Files.WatchFolder(&ListFolderInput{Path: "/photos", Recursive: true})
+
+	batch := <-changes
+	assert.Len(t, batch, 1)
+	assert.Equal(t, "/photos/1.jpg", batch[0].PathLower)
+
+	batch = <-changes
+	assert.Nil(t, batch, "reset signal")
+
+	batch = <-changes
+	assert.Len(t, batch, 1)
+	assert.Equal(t, "/photos/2.jpg", batch[0].PathLower)
+
+	batch = <-changes
+	assert.Len(t, batch, 1)
+	assert.Equal(t, "deleted", batch[0].Tag)
+
+	stop()
+
+	_, ok := <-changes
+	assert.False(t, ok, "channel should be closed")
+}
+
+func TestFiles_Watch_reset(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/list_folder/longpoll", r.URL.Path)
+		writeError(w, 409, "reset/..")
+	})
+
+	changes, stop := c.Files.Watch("cursor1")
+	defer stop()
+
+	batch, ok := <-changes
+	assert.True(t, ok)
+	assert.Nil(t, batch, "reset signal")
+
+	_, ok = <-changes
+	assert.False(t, ok, "channel should be closed")
+}
+
+func TestFiles_DeleteMany(t *testing.T) {
+	var mu sync.Mutex
+	batches := 0
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/delete_batch":
+			var in DeleteBatchInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.True(t, len(in.Entries) <= 1000)
+
+			mu.Lock()
+			batches++
+			mu.Unlock()
+
+			switch in.Entries[0].Path {
+			case "/file1000":
+				writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+				return
+			case "/file3000":
+				writeError(w, 409, "too_many_files/")
+				return
+			}
+
+			var entries []interface{}
+			for _, e := range in.Entries {
+				if e.Path == "/file5" {
+					entries = append(entries, map[string]interface{}{
+						".tag":    "failure",
+						"failure": map[string]interface{}{".tag": "path_lookup", "path_lookup": map[string]string{".tag": "not_found"}},
+					})
+					continue
+				}
+				entries = append(entries, map[string]interface{}{".tag": "success", "metadata": map[string]string{".tag": "file", "path_lower": e.Path}})
+			}
+			writeJSON(w, 200, map[string]interface{}{".tag": "complete", "entries": entries})
+		case "/2/files/delete_batch/check_job_status":
+			var entries []interface{}
+			for i := 1000; i < 2000; i++ {
+				entries = append(entries, map[string]interface{}{".tag": "success", "metadata": map[string]string{".tag": "file", "path_lower": fmt.Sprintf("/file%d", i)}})
+			}
+			writeJSON(w, 200, map[string]interface{}{".tag": "complete", "entries": entries})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	var paths []string
+	for i := 0; i < 2500; i++ {
+		paths = append(paths, fmt.Sprintf("/file%d", i))
+	}
+
+	results, err := c.Files.DeleteMany(paths, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, batches)
+	assert.Len(t, results, 2500)
+
+	for i := 2500; i < 3500; i++ {
+		paths = append(paths, fmt.Sprintf("/file%d", i))
+	}
+
+	batches = 0
+	results, err = c.Files.DeleteMany(paths, 4)
+	assert.True(t, hasTag(err, "too_many_files"))
+	assert.Equal(t, 4, batches)
+	assert.Len(t, results, 3500)
+
+	for i, res := range results {
+		if i >= 3000 {
+			assert.Equal(t, "failure", res.Tag)
+			assert.Equal(t, err, res.Err)
+			continue
+		}
+
+		assert.NoError(t, res.Err)
+		if i == 5 {
+			assert.Equal(t, "failure", res.Tag)
+			assert.Equal(t, "not_found", res.Failure.PathLookup.Tag)
+			continue
+		}
+		assert.Equal(t, "success", res.Tag)
+		assert.Equal(t, paths[i], res.Metadata.PathLower)
+	}
+}
+
+func TestFiles_Upload_expectRev(t *testing.T) {
+	rev := "a1"
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/upload":
+			var in struct {
+				Mode struct {
+					Tag    string `json:".tag"`
+					Update string `json:"update"`
+				} `json:"mode"`
+			}
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "update", in.Mode.Tag)
+
+			if in.Mode.Update != rev {
+				writeError(w, 409, "path/conflict/file/..")
+				return
+			}
+
+			rev = "b2"
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/doc.txt", "rev": rev})
+		case "/2/files/get_metadata":
+			writeJSON(w, 200, map[string]string{".tag": "file", "rev": rev})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	out, err := c.Files.Upload(&UploadInput{
+		Path:      "/doc.txt",
+		ExpectRev: "a1",
+		Reader:    bytes.NewBufferString("hello"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "b2", out.Rev)
+
+	_, err = c.Files.Upload(&UploadInput{
+		Path:      "/doc.txt",
+		ExpectRev: "a1",
+		Reader:    bytes.NewBufferString("world"),
+	})
+	assert.Equal(t, &RevConflictError{Path: "/doc.txt", ExpectRev: "a1", Rev: "b2"}, err)
+	assert.EqualError(t, err, "dropbox: /doc.txt is at rev b2, expected a1")
+}
+
+func TestMediaInfo_UnmarshalJSON(t *testing.T) {
+	var pending Metadata
+	assert.NoError(t, json.Unmarshal([]byte(`{".tag": "file", "media_info": {".tag": "pending"}}`), &pending))
+	assert.True(t, pending.MediaInfo.IsPending())
+	assert.Nil(t, pending.MediaInfo.Metadata)
+
+	var photo Metadata
+	assert.NoError(t, json.Unmarshal([]byte(`{".tag": "file", "media_info": {".tag": "metadata", "metadata": {
+		".tag": "photo",
+		"dimensions": {"height": 1500, "width": 1500},
+		"location": {"latitude": 10.123456, "longitude": 5.123456},
+		"time_taken": "2015-05-12T15:50:38Z"
+	}}}`), &photo))
+	assert.False(t, photo.MediaInfo.IsPending())
+	p := photo.MediaInfo.Metadata.Photo
+	assert.Equal(t, &Dimensions{Width: 1500, Height: 1500}, p.Dimensions)
+	assert.Equal(t, &GPSCoordinates{Latitude: 10.123456, Longitude: 5.123456}, p.Location)
+	assert.Equal(t, time.Date(2015, 5, 12, 15, 50, 38, 0, time.UTC), p.TimeTaken)
+	assert.Nil(t, photo.MediaInfo.Metadata.Video)
+
+	var video Metadata
+	assert.NoError(t, json.Unmarshal([]byte(`{".tag": "file", "media_info": {".tag": "metadata", "metadata": {
+		".tag": "video",
+		"dimensions": {"height": 720, "width": 1280},
+		"time_taken": "",
+		"duration": 8000
+	}}}`), &video))
+	v := video.MediaInfo.Metadata.Video
+	assert.Equal(t, uint64(8000), v.Duration)
+	assert.Nil(t, v.Location)
+	assert.True(t, v.TimeTaken.IsZero())
+}
+
+func TestFiles_ListModifiedSince(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/list_folder":
+			var in ListFolderInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "/backup", in.Path)
+			assert.True(t, in.Recursive)
+
+			writeJSON(w, 200, map[string]interface{}{
+				"entries": []map[string]string{
+					{".tag": "folder", "path_lower": "/backup/sub"},
+					{".tag": "file", "path_lower": "/backup/old.txt", "server_modified": "2020-01-01T00:00:00Z"},
+					{".tag": "file", "path_lower": "/backup/same.txt", "server_modified": "2020-06-01T00:00:00Z"},
+				},
+				"cursor":   "cursor1",
+				"has_more": true,
+			})
+		case "/2/files/list_folder/continue":
+			writeJSON(w, 200, map[string]interface{}{
+				"entries": []map[string]string{
+					{".tag": "deleted", "path_lower": "/backup/gone.txt"},
+					{".tag": "file", "path_lower": "/backup/sub/new.txt", "server_modified": "2021-01-01T00:00:00Z"},
+				},
+				"cursor": "cursor2",
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	files, err := c.Files.ListModifiedSince("/backup", time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, "/backup/same.txt", files[0].PathLower)
+	assert.Equal(t, "/backup/sub/new.txt", files[1].PathLower)
+}
+
+func TestFiles_GetMetadataMany(t *testing.T) {
+	var mu sync.Mutex
+	limited := false
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in GetMetadataInput
+		assert.NoError(t, decodeArg(r, &in))
+
+		switch in.Path {
+		case "/a.txt":
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+		case "/missing.txt":
+			writeError(w, 409, "path/not_found/..")
+		case "/broken.txt":
+			writeError(w, 500, "internal_error/")
+		case "/limited.txt":
+			mu.Lock()
+			defer mu.Unlock()
+			if !limited {
+				limited = true
+				writeError(w, 429, "too_many_requests/")
+				return
+			}
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/limited.txt"})
+		}
+	})
+
+	c.MaxRetries = 1
+	c.RetryBackoff = func(int) time.Duration { return time.Millisecond }
+
+	results := c.Files.GetMetadataMany([]string{"/a.txt", "/missing.txt", "/broken.txt", "/limited.txt"}, 2)
+	assert.Len(t, results, 4)
+
+	assert.Equal(t, "/a.txt", results[0].Metadata.PathLower)
+	assert.NoError(t, results[0].Err)
+
+	assert.Equal(t, "/missing.txt", results[1].Path)
+	assert.True(t, results[1].NotFound)
+	assert.Nil(t, results[1].Metadata)
+	assert.NoError(t, results[1].Err)
+
+	assert.False(t, results[2].NotFound)
+	assert.Error(t, results[2].Err)
+
+	assert.Equal(t, "/limited.txt", results[3].Metadata.PathLower)
+	assert.True(t, limited)
+
+	batch := c.Files.GetMetadataBatch([]string{"/a.txt", "/missing.txt"}, 2)
+	assert.Len(t, batch, 2)
+	assert.Equal(t, "/a.txt", batch["/a.txt"].Metadata.PathLower)
+	assert.True(t, batch["/missing.txt"].NotFound)
+}
+
+func TestFiles_ListFolderContinue_emptyCursor(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	})
+
+	_, err := c.Files.ListFolderContinue(&ListFolderContinueInput{})
+	assert.Equal(t, ErrEmptyCursor, err)
+}
+
+func TestFiles_ListFolderAll(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/list_folder":
+			writeJSON(w, 200, map[string]interface{}{
+				"entries":  []map[string]string{{".tag": "file", "path_lower": "/a.txt"}},
+				"cursor":   "cursor1",
+				"has_more": true,
+			})
+		case "/2/files/list_folder/continue":
+			var in ListFolderContinueInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			switch in.Cursor {
+			case "cursor1":
+				writeJSON(w, 200, map[string]interface{}{
+					"entries":  []map[string]string{{".tag": "file", "path_lower": "/b.txt"}},
+					"cursor":   "cursor2",
+					"has_more": true,
+				})
+			case "cursor2":
+				writeJSON(w, 200, map[string]interface{}{
+					"entries": []map[string]string{{".tag": "file", "path_lower": "/c.txt"}},
+					"cursor":  "cursor3",
+				})
+			default:
+				writeError(w, 409, "reset/..")
+			}
+		}
+	})
+
+	entries, err := c.Files.ListFolderAll(&ListFolderInput{Path: "/"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "/c.txt", entries[2].PathLower)
+
+	c = mockClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/2/files/list_folder" {
+			writeJSON(w, 200, map[string]interface{}{
+				"entries":  []map[string]string{{".tag": "file", "path_lower": "/a.txt"}},
+				"cursor":   "cursor1",
+				"has_more": true,
+			})
+			return
+		}
+		writeError(w, 409, "reset/..")
+	})
+
+	entries, err = c.Files.ListFolderAll(&ListFolderInput{Path: "/"})
+	assert.Equal(t, ErrCursorReset, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestFiles_UploadLarge(t *testing.T) {
+	var offsets []uint64
+	var stored []byte
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+
+		switch r.URL.Path {
+		case "/2/files/upload_session/start":
+			stored = data
+			writeJSON(w, 200, map[string]string{"session_id": "session"})
+		case "/2/files/upload_session/append_v2":
+			var in UploadSessionAppendInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "session", in.Cursor.SessionID)
+			offsets = append(offsets, in.Cursor.Offset)
+			stored = append(stored, data...)
+			writeJSON(w, 200, nil)
+		case "/2/files/upload_session/finish":
+			var in UploadSessionFinishInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, uint64(len(stored)), in.Cursor.Offset)
+			assert.Equal(t, "/big.bin", in.Commit.Path)
+			assert.True(t, in.Commit.AutoRename)
+			assert.Equal(t, "ptid:1", in.Commit.PropertyGroups[0].TemplateID)
+			stored = append(stored, data...)
+			writeJSON(w, 200, map[string]interface{}{".tag": "file", "path_lower": "/big.bin", "size": len(stored)})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), (2*uploadChunkSize+100)/16)
+
+	out, err := c.Files.UploadLarge(&UploadInput{
+		Path:           "/big.bin",
+		AutoRename:     true,
+		Reader:         bytes.NewReader(data),
+		PropertyGroups: []*PropertyGroup{{TemplateID: "ptid:1"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len(data)), out.Size)
+	assert.Equal(t, []uint64{uploadChunkSize}, offsets)
+	assert.Equal(t, data, stored)
+}
+
+func TestFiles_Watch_stopDuringContinue(t *testing.T) {
+	started := make(chan struct{})
+	var once sync.Once
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/list_folder/longpoll":
+			writeJSON(w, 200, map[string]interface{}{"changes": true})
+		case "/2/files/list_folder/continue":
+			once.Do(func() { close(started) })
+			<-r.Context().Done()
+		}
+	})
+
+	_, stop := c.Files.Watch("cursor1")
+	<-started
+
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop blocked on the in-flight continue request")
+	}
+}
+
+func TestFiles_DeleteMany_cancel(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/delete_batch":
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+		default:
+			writeJSON(w, 200, map[string]string{".tag": "in_progress"})
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Files.WithContext(ctx).DeleteMany([]string{"/a.txt"}, 1)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestFiles_GetMetadataMany_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	requests := 0
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		cancel()
+		writeJSON(w, 200, map[string]string{".tag": "file"})
+	})
+
+	results := c.Files.WithContext(ctx).GetMetadataMany([]string{"/a.txt", "/b.txt", "/c.txt"}, 1)
+	assert.Equal(t, 1, requests)
+	assert.Len(t, results, 3)
+	for _, res := range results {
+		assert.ErrorIs(t, res.Err, context.Canceled)
+	}
+}
+
+func TestFiles_Download_metadata(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/download", r.URL.Path)
+		w.Header().Set("Dropbox-API-Result", `{".tag": "file", "name": "a.txt", "path_lower": "/a.txt", "rev": "a1c10ce0dd78", "size": 5, "server_modified": "2015-05-12T15:50:38Z", "content_hash": "e3b0c4"}`)
+		w.Write([]byte("hello"))
+	})
+
+	out, err := c.Files.Download(&DownloadInput{Path: "/a.txt"})
+	assert.NoError(t, err)
+	defer out.Body.Close()
+
+	assert.Equal(t, "a.txt", out.Metadata.Name)
+	assert.Equal(t, "a1c10ce0dd78", out.Metadata.Rev)
+	assert.Equal(t, uint64(5), out.Metadata.Size)
+	assert.Equal(t, "e3b0c4", out.Metadata.ContentHash)
+	assert.Equal(t, time.Date(2015, 5, 12, 15, 50, 38, 0, time.UTC), out.Metadata.ServerModified)
+
+	data, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestFiles_GetThumbnail_mode(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/get_thumbnail", r.URL.Path)
+
+		var in GetThumbnailInput
+		assert.NoError(t, decodeArg(r, &in))
+
+		if in.Path == "/doc.txt" {
+			writeError(w, 409, "unsupported_extension/")
+			return
+		}
+
+		assert.Equal(t, ThumbnailFormat(GetThumbnailFormatPNG), in.Format)
+		assert.Equal(t, ThumbnailSize(GetThumbnailSizeW640H480), in.Size)
+		assert.Equal(t, ThumbnailMode(GetThumbnailModeBestFit), in.Mode)
+		w.Write([]byte("png"))
+	})
+
+	out, err := c.Files.GetThumbnail(&GetThumbnailInput{
+		Path:   "/photo.jpg",
+		Format: GetThumbnailFormatPNG,
+		Size:   GetThumbnailSizeW640H480,
+		Mode:   GetThumbnailModeBestFit,
+	})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(data))
+	out.Body.Close()
+
+	_, err = c.Files.GetThumbnail(&GetThumbnailInput{Path: "/doc.txt"})
+	assert.Equal(t, "unsupported_extension", err.(*Error).Tag())
+}
+
+func TestFiles_GetThumbnailV2(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/get_thumbnail_v2", r.URL.Path)
+
+		var in struct {
+			Resource map[string]string `json:"resource"`
+		}
+		assert.NoError(t, decodeArg(r, &in))
+
+		switch in.Resource[".tag"] {
+		case "path":
+			assert.Equal(t, map[string]string{".tag": "path", "path": "id:a4ayc_80_OEAAAAAAAAAYa"}, in.Resource)
+			w.Header().Set("Dropbox-API-Result", `{"file_metadata": {"name": "photo.jpg", "id": "id:a4ayc_80_OEAAAAAAAAAYa", "path_lower": "/photo.jpg"}}`)
+		case "link":
+			assert.Equal(t, map[string]string{".tag": "link", "url": "https://www.dropbox.com/sh/abc", "password": "secret", "path": "/photo.jpg"}, in.Resource)
+			w.Header().Set("Dropbox-API-Result", `{"link_metadata": {"url": "https://www.dropbox.com/sh/abc", "rev": "a1c10ce0dd78"}}`)
+		}
+		w.Write([]byte("jpeg"))
+	})
+
+	out, err := c.Files.GetThumbnailV2(&GetThumbnailV2Input{
+		Resource: ThumbnailResource{Path: "id:a4ayc_80_OEAAAAAAAAAYa"},
+		Format:   GetThumbnailFormatJPEG,
+		Size:     GetThumbnailSizeW64H64,
+	})
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", string(data))
+	out.Body.Close()
+	assert.Equal(t, "/photo.jpg", out.FileMetadata.PathLower)
+	assert.Nil(t, out.LinkMetadata)
+
+	out, err = c.Files.GetThumbnailV2(&GetThumbnailV2Input{
+		Resource: ThumbnailResource{
+			Path: "/photo.jpg",
+			Link: &SharedLink{URL: "https://www.dropbox.com/sh/abc", Password: "secret"},
+		},
+	})
+	assert.NoError(t, err)
+	out.Body.Close()
+	assert.Nil(t, out.FileMetadata)
+	assert.Equal(t, "a1c10ce0dd78", out.LinkMetadata.Rev)
+}
+
+func TestFiles_GetTemporaryLink(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/get_temporary_link", r.URL.Path)
+
+		var in GetTemporaryLinkInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/video.mp4", in.Path)
+
+		writeJSON(w, 200, map[string]interface{}{
+			"metadata": map[string]string{".tag": "file", "path_lower": "/video.mp4", "rev": "a1"},
+			"link":     "https://dl.dropboxusercontent.com/apitl/1/abc",
+		})
+	})
+
+	out, err := c.Files.GetTemporaryLink(&GetTemporaryLinkInput{Path: "/video.mp4"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://dl.dropboxusercontent.com/apitl/1/abc", out.Link)
+	assert.Equal(t, "a1", out.Metadata.Rev)
+}
+
+func TestFiles_ListRevisions_deleted(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in ListRevisionsInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, uint64(5), in.Limit)
+
+		writeJSON(w, 200, map[string]interface{}{
+			"is_deleted":     true,
+			"server_deleted": "2020-01-02T03:04:05Z",
+			"entries": []map[string]interface{}{
+				{".tag": "file", "rev": "b2", "server_modified": "2020-01-01T00:00:00Z"},
+				{".tag": "file", "rev": "a1"},
+			},
+		})
+	})
+
+	out, err := c.Files.ListRevisions(&ListRevisionsInput{Path: "/doc.txt", Limit: 5})
+	assert.NoError(t, err)
+	assert.True(t, out.IsDeleted)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), out.ServerDeleted)
+	assert.Len(t, out.Entries, 2)
+	assert.Equal(t, "b2", out.Entries[0].Rev)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), out.Entries[0].ServerModified)
+}
+
+func TestFiles_SaveURLWait(t *testing.T) {
+	polls := 0
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/save_url":
+			var in SaveURLInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "https://example.com/a.zip", in.URL)
+
+			switch in.Path {
+			case "/bad.zip":
+				writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "bad"})
+			case "/slow.zip":
+				writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "slow"})
+			default:
+				writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+			}
+		case "/2/files/save_url/check_job_status":
+			var in SaveURLCheckJobStatusInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			switch in.AsyncJobID {
+			case "bad":
+				writeJSON(w, 200, map[string]interface{}{".tag": "failed", "failed": map[string]string{".tag": "download_failed"}})
+				return
+			case "slow":
+				writeJSON(w, 200, map[string]string{".tag": "in_progress"})
+				return
+			}
+
+			polls++
+			if polls == 1 {
+				writeJSON(w, 200, map[string]string{".tag": "in_progress"})
+				return
+			}
+			writeJSON(w, 200, map[string]interface{}{".tag": "complete", "path_lower": "/a.zip", "size": 10})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	m, err := c.Files.SaveURLWait(&SaveURLInput{Path: "/a.zip", URL: "https://example.com/a.zip"}, &PollOptions{Interval: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, polls)
+	assert.Equal(t, "file", m.Tag)
+	assert.Equal(t, "/a.zip", m.PathLower)
+	assert.Equal(t, uint64(10), m.Size)
+
+	_, err = c.Files.SaveURLWait(&SaveURLInput{Path: "/bad.zip", URL: "https://example.com/a.zip"}, &PollOptions{Interval: time.Millisecond})
+	assert.EqualError(t, err, "dropbox: saving url: download_failed")
+
+	_, err = c.Files.SaveURLWait(&SaveURLInput{Path: "/slow.zip", URL: "https://example.com/a.zip"}, &PollOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond})
+	assert.Equal(t, ErrJobTimeout, err)
+}
+
+func TestFiles_DeleteBatchWait(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/delete_batch":
+			var in DeleteBatchInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			if in.Entries[0].Path == "/slow.txt" {
+				writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "slow"})
+				return
+			}
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+		case "/2/files/delete_batch/check_job_status":
+			var in DeleteBatchCheckJobStatusInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			if in.AsyncJobID == "slow" {
+				writeJSON(w, 200, map[string]string{".tag": "in_progress"})
+				return
+			}
+			writeJSON(w, 200, map[string]interface{}{".tag": "failed", "failed": map[string]string{".tag": "too_many_write_operations"}})
+		}
+	})
+
+	opts := &PollOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond}
+
+	_, err := c.Files.DeleteBatchWait(&DeleteBatchInput{Entries: []*DeleteInput{{Path: "/a.txt"}}}, opts)
+	assert.EqualError(t, err, "dropbox: deleting files: too_many_write_operations")
+
+	_, err = c.Files.DeleteBatchWait(&DeleteBatchInput{Entries: []*DeleteInput{{Path: "/slow.txt"}}}, opts)
+	assert.Equal(t, ErrJobTimeout, err)
+}
+
+func TestFiles_MoveBatch(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/move_batch_v2":
+			var in MoveBatchInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, []RelocationPath{{FromPath: "/a.txt", ToPath: "/archive/a.txt"}}, in.Entries)
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+		case "/2/files/move_batch/check_job_status_v2":
+			var in MoveBatchCheckJobStatusInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "job", in.AsyncJobID)
+			writeJSON(w, 200, map[string]interface{}{
+				".tag":    "complete",
+				"entries": []interface{}{map[string]interface{}{".tag": "success", "success": map[string]string{".tag": "file", "path_lower": "/archive/a.txt"}}},
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	out, err := c.Files.MoveBatch(&MoveBatchInput{Entries: []RelocationPath{{FromPath: "/a.txt", ToPath: "/archive/a.txt"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "async_job_id", out.Tag)
+
+	status, err := c.Files.MoveBatchCheckJobStatus(&MoveBatchCheckJobStatusInput{out.AsyncJobID})
+	assert.NoError(t, err)
+	assert.Equal(t, "complete", status.Tag)
+	assert.Equal(t, "/archive/a.txt", status.Entries[0].Success.PathLower)
+}
+
+func TestFiles_MoveBatchCheckJobStatus_failures(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			".tag": "complete",
+			"entries": [
+				{".tag": "success", "success": {".tag": "file", "path_lower": "/archive/a.txt"}},
+				{".tag": "failure", "failure": {".tag": "relocation_error", "relocation_error": {".tag": "from_lookup", "from_lookup": {".tag": "not_found"}}}},
+				{".tag": "failure", "failure": {".tag": "relocation_error", "relocation_error": {".tag": "to", "to": {".tag": "conflict", "conflict": {".tag": "file"}}}}},
+				{".tag": "failure", "failure": {".tag": "relocation_error", "relocation_error": {".tag": "too_many_files"}}},
+				{".tag": "failure", "failure": {".tag": "too_many_write_operations"}}
+			]
+		}`))
+	})
+
+	status, err := c.Files.MoveBatchCheckJobStatus(&MoveBatchCheckJobStatusInput{"job"})
+	assert.NoError(t, err)
+	assert.Len(t, status.Entries, 5)
+
+	assert.Equal(t, "/archive/a.txt", status.Entries[0].Success.PathLower)
+	assert.Nil(t, status.Entries[0].Failure)
+
+	var reasons []string
+	for _, e := range status.Entries[1:] {
+		assert.Equal(t, "failure", e.Tag)
+		reasons = append(reasons, e.Failure.Error())
+	}
+
+	assert.Equal(t, []string{
+		"dropbox: relocating: relocation_error/from_lookup/not_found",
+		"dropbox: relocating: relocation_error/to/conflict/file",
+		"dropbox: relocating: relocation_error/too_many_files",
+		"dropbox: relocating: too_many_write_operations",
+	}, reasons)
+	assert.Equal(t, "not_found", status.Entries[1].Failure.RelocationError.FromLookup.Tag)
+}
+
+func TestFiles_MoveBatchWait(t *testing.T) {
+	checks := 0
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/move_batch_v2":
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+		case "/2/files/move_batch/check_job_status_v2":
+			if checks++; checks < 3 {
+				writeJSON(w, 200, map[string]string{".tag": "in_progress"})
+				return
+			}
+			writeJSON(w, 200, map[string]interface{}{
+				".tag":    "complete",
+				"entries": []interface{}{map[string]interface{}{".tag": "success", "success": map[string]string{".tag": "file", "path_lower": "/archive/a.txt"}}},
+			})
+		}
+	})
+
+	entries, err := c.Files.MoveBatchWait(&MoveBatchInput{Entries: []RelocationPath{{FromPath: "/a.txt", ToPath: "/archive/a.txt"}}}, &PollOptions{Interval: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, checks)
+	assert.Equal(t, "/archive/a.txt", entries[0].Success.PathLower)
+}
+
+func TestContentHash_blocks(t *testing.T) {
+	hash, err := ContentHash(bytes.NewReader(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hash)
+
+	data := bytes.Repeat([]byte("a"), 4*1024*1024+1)
+	first := sha256.Sum256(data[:4*1024*1024])
+	second := sha256.Sum256(data[4*1024*1024:])
+	expected := sha256.Sum256(append(first[:], second[:]...))
+
+	hash, err = ContentHash(iotest.OneByteReader(bytes.NewReader(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(expected[:]), hash)
+}
+
+func TestMetadata_fields(t *testing.T) {
+	var m Metadata
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		".tag": "file",
+		"content_hash": "e3b0c4",
+		"is_downloadable": true,
+		"sharing_info": {"read_only": true, "parent_shared_folder_id": "84528192421", "modified_by": "dbid:1"},
+		"property_groups": [{"template_id": "ptid:1a5n2i6d3OYEAAAAAAAAAYa", "fields": [{"name": "Security Policy", "value": "Confidential"}]}]
+	}`), &m))
+
+	assert.Equal(t, "e3b0c4", m.ContentHash)
+	assert.True(t, m.IsDownloadable)
+	assert.True(t, m.SharingInfo.ReadOnly)
+	assert.Equal(t, "84528192421", m.SharingInfo.ParentSharedFolderID)
+	assert.Equal(t, "dbid:1", m.SharingInfo.ModifiedBy)
+	assert.Equal(t, []*PropertyGroup{{
+		TemplateID: "ptid:1a5n2i6d3OYEAAAAAAAAAYa",
+		Fields:     []PropertyField{{Name: "Security Policy", Value: "Confidential"}},
+	}}, m.PropertyGroups)
+
+	f := m.Entry().(*FileMetadata)
+	assert.True(t, f.IsDownloadable)
+	assert.Len(t, f.PropertyGroups, 1)
+}
+
+func TestFiles_ListFolderGetLatestCursor(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/list_folder/get_latest_cursor", r.URL.Path)
+
+		var in ListFolderInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/photos", in.Path)
+		assert.True(t, in.Recursive)
+
+		writeJSON(w, 200, map[string]string{"cursor": "ZtkX9_EHj3x7PMkVuFIhwKYXEpwpLwyxp9vMKomUhllil9q7eWiAu"})
+	})
+
+	out, err := c.Files.ListFolderGetLatestCursor(&ListFolderInput{Path: "/photos", Recursive: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "ZtkX9_EHj3x7PMkVuFIhwKYXEpwpLwyxp9vMKomUhllil9q7eWiAu", out.Cursor)
+}
+
+func TestFiles_ListFolderLongpoll(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "notify.dropboxapi.com", r.URL.Host)
+		assert.Equal(t, "/2/files/list_folder/longpoll", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		var in ListFolderLongpollInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "cursor1", in.Cursor)
+		assert.Equal(t, uint64(60), in.Timeout)
+
+		writeJSON(w, 200, map[string]interface{}{"changes": true, "backoff": 30})
+	})
+
+	out, err := c.Files.ListFolderLongpoll(&ListFolderLongpollInput{Cursor: "cursor1", Timeout: 60})
+	assert.NoError(t, err)
+	assert.True(t, out.Changes)
+	assert.Equal(t, uint64(30), out.Backoff)
+}
+
+func TestWriteModeUpdate(t *testing.T) {
+	b, err := json.Marshal(&CommitInfo{Path: "/a.txt", Mode: WriteModeUpdate("a1c10ce0dd78")})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"path": "/a.txt", "mode": {".tag": "update", "update": "a1c10ce0dd78"}, "autorename": false, "mute": false}`, string(b))
+
+	var commit CommitInfo
+	assert.NoError(t, json.Unmarshal(b, &commit))
+	assert.Equal(t, "a1c10ce0dd78", commit.Mode.Rev())
+
+	b, err = json.Marshal(&CommitInfo{Path: "/a.txt", Mode: WriteModeOverwrite})
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"mode":"overwrite"`)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"mode": {".tag": "add"}}`), &commit))
+	assert.Equal(t, WriteModeAdd, commit.Mode)
+	assert.Empty(t, commit.Mode.Rev())
+}
+
+func TestFiles_Upload_writeModeUpdate(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/upload":
+			var in UploadInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "a1", in.Mode.Rev())
+			writeError(w, 409, "path/conflict/file/..")
+		case "/2/files/get_metadata":
+			writeJSON(w, 200, map[string]string{".tag": "file", "rev": "b2"})
+		}
+	})
+
+	_, err := c.Files.Upload(&UploadInput{
+		Path:   "/doc.txt",
+		Mode:   WriteModeUpdate("a1"),
+		Reader: bytes.NewBufferString("hello"),
+	})
+	assert.Equal(t, &RevConflictError{Path: "/doc.txt", ExpectRev: "a1", Rev: "b2"}, err)
+}
+
+func TestFiles_Upload_progress(t *testing.T) {
+	attempts := 0
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		if attempts++; attempts == 1 {
+			w.WriteHeader(500)
+			return
+		}
+		writeJSON(w, 200, map[string]string{".tag": "file", "name": "a.txt"})
+	})
+	c.MaxRetries = 1
+	c.RetryBackoff = func(int) time.Duration { return time.Millisecond }
+
+	var written, total []int64
+	_, err := c.Files.Upload(&UploadInput{
+		Path:   "/a.txt",
+		Reader: bytes.NewReader([]byte("hello")),
+		ProgressFunc: func(n, t int64) {
+			written = append(written, n)
+			total = append(total, t)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{5, 5}, written, "count restarts on retry")
+	assert.Equal(t, []int64{5, 5}, total)
+}
+
+func TestFiles_Upload_progressStream(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		writeJSON(w, 200, map[string]string{".tag": "file", "name": "a.txt"})
+	})
+
+	var written, total int64
+	_, err := c.Files.Upload(&UploadInput{
+		Path:   "/a.txt",
+		Reader: iotest.OneByteReader(bytes.NewBufferString("hello")),
+		ProgressFunc: func(n, t int64) {
+			written, total = n, t
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), written)
+	assert.Equal(t, int64(-1), total)
+}
+
+func TestFiles_Download_range(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bytes=5-9", r.Header.Get("Range"))
+		assert.Equal(t, `{"path":"/a.txt"}`, r.Header.Get("Dropbox-API-Arg"))
+		w.Header().Set("Content-Range", "bytes 5-9/20")
+		w.WriteHeader(206)
+		io.WriteString(w, "56789")
+	})
+
+	out, err := c.Files.Download(&DownloadInput{Path: "/a.txt", Start: 5, Length: 5})
+	assert.NoError(t, err)
+	defer out.Body.Close()
+
+	b, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "56789", string(b))
+	assert.Equal(t, int64(20), out.Total)
+}
+
+func TestDownloadInput_header(t *testing.T) {
+	assert.Nil(t, (&DownloadInput{}).header())
+	assert.Equal(t, "bytes=100-", (&DownloadInput{Start: 100}).header().Get("Range"))
+	assert.Equal(t, "bytes=0-9", (&DownloadInput{Length: 10}).header().Get("Range"))
+}
+
+func TestFiles_GetPreview_contentType(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/get_preview", r.URL.Path)
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Length", "15")
+		io.WriteString(w, "<table></table>")
+	})
+
+	out, err := c.Files.GetPreview(&GetPreviewInput{"/sheet.xlsx"})
+	assert.NoError(t, err)
+	defer out.Body.Close()
+
+	assert.Equal(t, "text/html", out.ContentType)
+	assert.Equal(t, int64(15), out.Length)
+}
+
+func TestFiles_DownloadZip(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/download_zip", r.URL.Path)
+		assert.Equal(t, `{"path":"/photos"}`, r.Header.Get("Dropbox-API-Arg"))
+		w.Header().Set("Dropbox-API-Result", `{"metadata": {".tag": "folder", "name": "photos", "path_lower": "/photos"}}`)
+		io.WriteString(w, "PK")
+	})
+
+	out, err := c.Files.DownloadZip(&DownloadZipInput{"/photos"})
+	assert.NoError(t, err)
+	defer out.Body.Close()
+
+	assert.Equal(t, "folder", out.Metadata.Tag)
+	assert.Equal(t, "photos", out.Metadata.Name)
+
+	b, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "PK", string(b))
+}
+
+func TestFiles_GetTemporaryUploadLink(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/get_temporary_upload_link", r.URL.Path)
+
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"commit_info": {"path": "/a.txt", "mode": "overwrite", "autorename": false, "mute": false},
+			"duration": 3600
+		}`, string(b))
+
+		writeJSON(w, 200, map[string]string{"link": "https://content.dropboxapi.com/apitul/1/abc"})
+	})
+
+	out, err := c.Files.GetTemporaryUploadLink(&GetTemporaryUploadLinkInput{
+		CommitInfo: CommitInfo{Path: "/a.txt", Mode: WriteModeOverwrite},
+		Duration:   3600,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://content.dropboxapi.com/apitul/1/abc", out.Link)
+}
+
+func TestNormalizePath(t *testing.T) {
+	for p, want := range map[string]string{
+		"":           "",
+		"/":          "",
+		"/a/b":       "/a/b",
+		"/a//b/":     "/a/b",
+		"//a///b//":  "/a/b",
+		"id:a4ayc_8": "id:a4ayc_8",
+		"ns:123/a/":  "ns:123/a/",
+	} {
+		got, err := NormalizePath(p)
+		assert.NoError(t, err, p)
+		assert.Equal(t, want, got, p)
+	}
+
+	_, err := NormalizePath("a/b.txt")
+	assert.Equal(t, &InvalidPathError{"a/b.txt"}, err)
+}
+
+func TestFiles_Download_normalizePath(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{"path":"/a/b.txt"}`, r.Header.Get("Dropbox-API-Arg"))
+	})
+
+	out, err := c.Files.Download(&DownloadInput{Path: "/a//b.txt/"})
+	assert.NoError(t, err)
+	out.Body.Close()
+}
+
+func TestFiles_Search_v2(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/search_v2", r.URL.Path)
+
+		var in SearchV2Input
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, &SearchOptions{Path: "/docs", MaxResults: 10, FileStatus: "deleted", FilenameOnly: true}, in.Options)
+
+		writeJSON(w, 200, map[string]interface{}{
+			"matches": []interface{}{
+				map[string]interface{}{
+					"match_type": map[string]string{".tag": "filename"},
+					"metadata":   map[string]interface{}{".tag": "metadata", "metadata": map[string]string{".tag": "deleted", "name": "a.txt"}},
+				},
+			},
+			"has_more": true,
+			"cursor":   "c1",
+		})
+	})
+
+	out, err := c.Files.Search(&SearchInput{Path: "/docs/", Query: "a", MaxResults: 10, Mode: SearchModeDeletedFilename})
+	assert.NoError(t, err)
+	assert.True(t, out.More)
+	assert.Equal(t, "c1", out.Cursor)
+	assert.Len(t, out.Matches, 1)
+	assert.Equal(t, SearchMatchFilename, out.Matches[0].MatchType.Tag)
+	assert.Equal(t, "a.txt", out.Matches[0].Metadata.Name)
+
+	_, err = c.Files.SearchContinue(&SearchContinueInput{})
+	assert.Equal(t, ErrEmptyCursor, err)
+}
+
+func TestFiles_GetMetadata_includeDeleted(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+
+		if in["include_deleted"] != true {
+			writeError(w, 409, "path/not_found/..")
+			return
+		}
+		writeJSON(w, 200, map[string]string{".tag": "deleted", "name": "a.txt", "path_lower": "/a.txt"})
+	})
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt"})
+	assert.True(t, IsNotFound(err))
+
+	out, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.txt", IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.IsType(t, &DeletedMetadata{}, out.Entry())
+}
+
+func TestFiles_GetMetadata_mediaInfo(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in GetMetadataInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.True(t, in.IncludeMediaInfo)
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{".tag": "file", "name": "a.jpg", "media_info": {".tag": "metadata", "metadata": {
+			".tag": "photo", "dimensions": {"width": 640, "height": 480}, "time_taken": "2016-09-04T17:00:27Z"
+		}}}`)
+	})
+
+	out, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/a.jpg", IncludeMediaInfo: true})
+	assert.NoError(t, err)
+
+	file := out.Entry().(*FileMetadata)
+	assert.Equal(t, &Dimensions{640, 480}, file.MediaInfo.Metadata.Photo.Dimensions)
+	assert.Equal(t, 2016, file.MediaInfo.Metadata.Photo.TimeTaken.Year())
+}
+
+func TestFiles_Copy_v2(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/copy_v2", r.URL.Path)
+
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"from_path": "/a.txt", "to_path": "/shared/a.txt", "allow_shared_folder": true, "autorename": true}`, string(b))
+
+		writeJSON(w, 200, map[string]interface{}{"metadata": map[string]string{".tag": "file", "path_lower": "/shared/a (1).txt"}})
+	})
+
+	out, err := c.Files.Copy(&CopyInput{
+		FromPath:          "/a.txt",
+		ToPath:            "/shared/a.txt",
+		AllowSharedFolder: true,
+		AutoRename:        true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/shared/a (1).txt", out.PathLower)
+}
+
+func TestFiles_CreateFolderBatchWait(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/create_folder_batch":
+			var in CreateFolderBatchInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.True(t, in.ForceAsync)
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+		case "/2/files/create_folder_batch/check_job_status":
+			writeJSON(w, 200, map[string]interface{}{
+				".tag": "complete",
+				"entries": []interface{}{
+					map[string]interface{}{".tag": "success", "metadata": map[string]string{"path_lower": "/a"}},
+					map[string]interface{}{".tag": "failure", "failure": map[string]interface{}{".tag": "path", "path": map[string]string{".tag": "insufficient_space"}}},
+				},
+			})
+		}
+	})
+
+	entries, err := c.Files.CreateFolderBatchWait(&CreateFolderBatchInput{Paths: []string{"/a", "/b"}, ForceAsync: true}, &PollOptions{Interval: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "/a", entries[0].Metadata.PathLower)
+	assert.Equal(t, "insufficient_space", entries[1].Failure.Path.Tag)
+}
+
+func TestFiles_Export_format(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{"path":"/doc.gdoc","export_format":"pdf"}`, r.Header.Get("Dropbox-API-Arg"))
+		w.Header().Set("Dropbox-API-Result", `{"export_metadata": {"name": "doc.pdf"}, "file_metadata": {"name": "doc.gdoc", "export_info": {"export_as": "docx", "export_options": ["docx", "pdf"]}}}`)
+	})
+
+	out, err := c.Files.Export(&ExportInput{Path: "/doc.gdoc", ExportFormat: "pdf"})
+	assert.NoError(t, err)
+	out.Body.Close()
+
+	assert.Equal(t, "doc.pdf", out.ExportMetadata.Name)
+	assert.Equal(t, &ExportInfo{"docx", []string{"docx", "pdf"}}, out.FileMetadata.ExportInfo)
+}
+
+func TestFiles_Walk(t *testing.T) {
+	requests := 0
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/2/files/list_folder":
+			var in ListFolderInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "/docs", in.Path)
+			assert.True(t, in.Recursive)
+			writeJSON(w, 200, map[string]interface{}{
+				"entries":  []map[string]string{{".tag": "folder", "path_lower": "/docs/sub"}, {".tag": "file", "path_lower": "/docs/a.txt"}},
+				"cursor":   "c1",
+				"has_more": true,
+			})
+		case "/2/files/list_folder/continue":
+			writeJSON(w, 200, map[string]interface{}{
+				"entries":  []map[string]string{{".tag": "file", "path_lower": "/docs/sub/b.txt"}},
+				"cursor":   "c2",
+				"has_more": false,
+			})
+		}
+	})
+
+	var paths []string
+	err := c.Files.Walk("/docs", func(m *Metadata) error {
+		paths = append(paths, m.PathLower)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/docs/sub", "/docs/a.txt", "/docs/sub/b.txt"}, paths)
+
+	requests = 0
+	stop := errors.New("stop")
+	err = c.Files.Walk("/docs", func(m *Metadata) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, requests, "stops listing once fn fails")
+}
+
+func TestFiles_GetThumbnailBatch(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "content.dropboxapi.com", r.URL.Host)
+		assert.Equal(t, "/2/files/get_thumbnail_batch", r.URL.Path)
+
+		var in GetThumbnailBatchInput
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		assert.Len(t, in.Entries, 2)
+		assert.Equal(t, ThumbnailSize(GetThumbnailSizeW64H64), in.Entries[0].Size)
+
+		writeJSON(w, 200, map[string]interface{}{
+			"entries": []interface{}{
+				map[string]interface{}{".tag": "success", "metadata": map[string]string{"path_lower": "/a.jpg"}, "thumbnail": "/9j/4A=="},
+				map[string]interface{}{".tag": "failure", "failure": map[string]string{".tag": "unsupported_extension"}},
+			},
+		})
+	})
+
+	out, err := c.Files.GetThumbnailBatch(&GetThumbnailBatchInput{
+		Entries: []*GetThumbnailInput{
+			{Path: "/a.jpg", Format: GetThumbnailFormatJPEG, Size: GetThumbnailSizeW64H64},
+			{Path: "/b.txt", Format: GetThumbnailFormatJPEG, Size: GetThumbnailSizeW64H64},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, out.Entries, 2)
+	assert.Equal(t, "/a.jpg", out.Entries[0].Metadata.PathLower)
+	assert.Equal(t, []byte{0xff, 0xd8, 0xff, 0xe0}, out.Entries[0].Thumbnail)
+	assert.Equal(t, "unsupported_extension", out.Entries[1].Failure.Tag)
+
+	_, err = c.Files.GetThumbnailBatch(&GetThumbnailBatchInput{Entries: make([]*GetThumbnailInput, 26)})
+	assert.Error(t, err)
+}
+
+func TestFiles_LockFileBatch(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/files/lock_file_batch", r.URL.Path)
+
+		var in LockFileBatchInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, []*LockFileArg{{"/a.docx"}, {"/b.docx"}}, in.Entries)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"entries": [
+			{".tag": "success", "metadata": {".tag": "file", "path_lower": "/a.docx", "file_lock_info": {"is_lockholder": true}},
+			 "lock": {"content": {".tag": "single_user", "lock_holder_account_id": "dbid:me", "created": "2026-01-01T00:00:00Z"}}},
+			{".tag": "failure", "failure": {".tag": "lock_conflict", "lock": {"content": {".tag": "single_user", "lock_holder_account_id": "dbid:other"}}}}
+		]}`))
+	})
+
+	out, err := c.Files.LockFileBatch(&LockFileBatchInput{Entries: []*LockFileArg{{"/a.docx"}, {"/b.docx"}}})
+	assert.NoError(t, err)
+	assert.Len(t, out.Entries, 2)
+
+	a := out.Entries[0]
+	assert.Equal(t, "success", a.Tag)
+	assert.Equal(t, "dbid:me", a.Lock.Content.LockHolderAccountID)
+	assert.True(t, a.Metadata.FileLockInfo.IsLockholder)
+
+	b := out.Entries[1]
+	assert.True(t, IsLockConflict(b.Failure))
+	assert.EqualError(t, b.Failure, "dropbox: file is locked by dbid:other")
+}
+
+func TestIsLockConflict(t *testing.T) {
+	assert.True(t, IsLockConflict(&Error{Summary: "path/lock_conflict/.."}))
+	assert.False(t, IsLockConflict(&Error{Summary: "path/conflict/file/.."}))
+	assert.False(t, IsLockConflict(&LockFileError{Tag: "no_write_permission"}))
+	assert.False(t, IsLockConflict(nil))
+}
+
+func TestFiles_UploadLargeConcurrent(t *testing.T) {
+	data := make([]byte, 2*uploadChunkSize+100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	var mu sync.Mutex
+	got := make([]byte, len(data))
+	closed := map[uint64]bool{}
+	finished := false
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/2/files/upload_session/start":
+			var in UploadSessionStartInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, UploadSessionType(UploadSessionConcurrent), in.SessionType)
+			assert.Empty(t, b)
+			writeJSON(w, 200, map[string]string{"session_id": "s"})
+		case "/2/files/upload_session/append_v2":
+			var in UploadSessionAppendInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "s", in.Cursor.SessionID)
+			copy(got[in.Cursor.Offset:], b)
+			closed[in.Cursor.Offset] = in.Close
+			writeJSON(w, 200, nil)
+		case "/2/files/upload_session/finish":
+			var in UploadSessionFinishInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, uint64(len(data)), in.Cursor.Offset)
+			assert.Equal(t, "/big.bin", in.Commit.Path)
+			assert.Empty(t, b)
+			finished = true
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/big.bin"})
+		}
+	})
+
+	out, err := c.Files.UploadLargeConcurrent(&UploadInput{Path: "/big.bin", Reader: bytes.NewReader(data)}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "/big.bin", out.PathLower)
+	assert.True(t, finished)
+	assert.Equal(t, data, got)
+	assert.Equal(t, map[uint64]bool{0: false, uploadChunkSize: false, 2 * uploadChunkSize: true}, closed)
+}
+
+func TestFiles_UploadLargeConcurrent_error(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+
+		switch r.URL.Path {
+		case "/2/files/upload_session/start":
+			writeJSON(w, 200, map[string]string{"session_id": "s"})
+		case "/2/files/upload_session/append_v2":
+			var in UploadSessionAppendInput
+			assert.NoError(t, decodeArg(r, &in))
+			if in.Cursor.Offset > 0 {
+				writeError(w, 409, "incorrect_offset/..")
+				return
+			}
+			writeJSON(w, 200, nil)
+		case "/2/files/upload_session/finish":
+			t.Error("should not finish a failed session")
+		}
+	})
+
+	data := make([]byte, 2*uploadChunkSize)
+	_, err := c.Files.UploadLargeConcurrent(&UploadInput{Path: "/big.bin", Reader: bytes.NewReader(data)}, 2)
+	assert.True(t, hasTag(err, "incorrect_offset"))
+}
+
+func TestFiles_Delete_parentRev(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/delete_v2":
+			var in DeleteInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "/a.txt", in.Path)
+			if in.ParentRev == "a1" {
+				writeJSON(w, 200, map[string]interface{}{"metadata": map[string]string{".tag": "file", "path_lower": "/a.txt", "rev": "a1"}})
+				return
+			}
+			writeError(w, 409, "path_write/conflict/file/..")
+		case "/2/files/get_metadata":
+			writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt", "rev": "b2"})
+		default:
+			t.Fatalf("unexpected request %s", r.URL.Path)
+		}
+	})
+
+	out, err := c.Files.Delete(&DeleteInput{Path: "/a.txt", ParentRev: "a1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt", out.PathLower)
+
+	_, err = c.Files.Delete(&DeleteInput{Path: "/a.txt", ParentRev: "a0"})
+	conflict, ok := err.(*RevConflictError)
+	assert.True(t, ok)
+	assert.Equal(t, "a0", conflict.ExpectRev)
+	assert.Equal(t, "b2", conflict.Rev)
+}
+
+func TestFiles_Upload_propertyGroups(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.JSONEq(t, `{
+			"path": "/a.txt",
+			"mode": "add",
+			"autorename": false,
+			"mute": true,
+			"property_groups": [{"template_id": "ptid:1", "fields": [{"name": "Security Policy", "value": "Confidential"}]}]
+		}`, r.Header.Get("Dropbox-API-Arg"))
+		writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+	})
+
+	_, err := c.Files.Upload(&UploadInput{
+		Path:   "/a.txt",
+		Mode:   WriteModeAdd,
+		Mute:   true,
+		Reader: bytes.NewBufferString("hello"),
+		PropertyGroups: []*PropertyGroup{{
+			TemplateID: "ptid:1",
+			Fields:     []PropertyField{{Name: "Security Policy", Value: "Confidential"}},
+		}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestFiles_Upload_size(t *testing.T) {
+	var lengths []int64
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		lengths = append(lengths, r.ContentLength)
+		data, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "hello", string(data))
+		writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+	})
+
+	stream := func() io.Reader {
+		return io.MultiReader(bytes.NewBufferString("hel"), bytes.NewBufferString("lo"))
+	}
+
+	_, err := c.Files.Upload(&UploadInput{Path: "/a.txt", Reader: stream(), Size: 5})
+	assert.NoError(t, err)
+
+	_, err = c.Files.Upload(&UploadInput{Path: "/a.txt", Reader: stream(), Size: 5, ProgressFunc: func(written, total int64) {}})
+	assert.NoError(t, err)
+
+	_, err = c.Files.Upload(&UploadInput{Path: "/a.txt", Reader: stream()})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []int64{5, 5, 0}, lengths)
+}
+
+func TestFiles_ListFolder_sharedLink(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"path": "/photos",
+			"recursive": false,
+			"include_media_info": false,
+			"include_deleted": false,
+			"limit": 100,
+			"shared_link": {"url": "https://www.dropbox.com/sh/s6fvw6ol7rmqo1x/AAAgWRSbjmYDvPpDB30Sykjfa"}
+		}`, string(b))
+		writeJSON(w, 200, map[string]interface{}{"entries": []interface{}{}, "cursor": "c", "has_more": false})
+	})
+
+	_, err := c.Files.ListFolder(&ListFolderInput{
+		Path:       "/photos",
+		Limit:      100,
+		SharedLink: &SharedLink{URL: "https://www.dropbox.com/sh/s6fvw6ol7rmqo1x/AAAgWRSbjmYDvPpDB30Sykjfa"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestFiles_MoveWait(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/files/move_batch_v2":
+			var in MoveBatchInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.True(t, in.AllowOwnershipTransfer)
+			if in.Entries[0].FromPath == "/missing" {
+				writeJSON(w, 200, map[string]interface{}{
+					".tag":    "complete",
+					"entries": []interface{}{map[string]interface{}{".tag": "failure", "failure": map[string]interface{}{".tag": "relocation_error", "relocation_error": map[string]interface{}{".tag": "from_lookup", "from_lookup": map[string]string{".tag": "not_found"}}}}},
+				})
+				return
+			}
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "job"})
+		case "/2/files/move_batch/check_job_status_v2":
+			writeJSON(w, 200, map[string]interface{}{
+				".tag":    "complete",
+				"entries": []interface{}{map[string]interface{}{".tag": "success", "success": map[string]string{".tag": "folder", "path_lower": "/archive/photos"}}},
+			})
+		}
+	})
+
+	out, err := c.Files.MoveWait(&MoveInput{FromPath: "/photos", ToPath: "/archive/photos", AllowOwnershipTransfer: true}, &PollOptions{Interval: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, "/archive/photos", out.PathLower)
+
+	_, err = c.Files.MoveWait(&MoveInput{FromPath: "/missing", ToPath: "/archive/missing", AllowOwnershipTransfer: true}, nil)
+	assert.EqualError(t, err, "dropbox: relocating: relocation_error/from_lookup/not_found")
+}
+
+func TestFiles_Upload_clientModified(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in UploadInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "2026-05-01T10:00:00Z", in.ClientModified)
+		writeJSON(w, 200, map[string]string{".tag": "file", "path_lower": "/a.txt"})
+	})
+
+	modified := time.Date(2026, 5, 1, 12, 0, 0, 500, time.FixedZone("CEST", 2*60*60))
+	assert.Equal(t, "2026-05-01T10:00:00Z", FormatTime(modified))
+
+	_, err := c.Files.Upload(&UploadInput{
+		Path:           "/a.txt",
+		ClientModified: modified.Format(time.RFC3339Nano),
+		Reader:         bytes.NewBufferString("hello"),
+	})
+	assert.NoError(t, err)
+}
+
+func TestFiles_Upload_autoRename(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in UploadInput
+		assert.NoError(t, decodeArg(r, &in))
+
+		switch {
+		case in.AutoRename:
+			writeJSON(w, 200, map[string]string{".tag": "file", "name": "a (1).txt", "path_lower": "/a (1).txt"})
+		case in.Mode == WriteModeOverwrite:
+			writeJSON(w, 200, map[string]string{".tag": "file", "name": "a.txt", "path_lower": "/a.txt"})
+		default:
+			writeError(w, 409, "path/conflict/file/..")
+		}
+	})
+
+	t.Run("renamed", func(t *testing.T) {
+		out, err := c.Files.Upload(&UploadInput{Path: "/a.txt", AutoRename: true, Reader: bytes.NewBufferString("hello")})
+		assert.NoError(t, err)
+		assert.True(t, out.Renamed)
+		assert.Equal(t, "/a (1).txt", out.PathLower)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		out, err := c.Files.Upload(&UploadInput{Path: "/a.txt", Mode: WriteModeOverwrite, Reader: bytes.NewBufferString("hello")})
+		assert.NoError(t, err)
+		assert.False(t, out.Renamed)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		_, err := c.Files.Upload(&UploadInput{Path: "/a.txt", Reader: bytes.NewBufferString("hello")})
+		assert.EqualError(t, err, "dropbox: /a.txt conflicts with an existing file")
+		assert.True(t, IsConflict(err))
+
+		e, ok := err.(*PathConflictError)
+		assert.True(t, ok)
+		assert.Equal(t, "/a.txt", e.Path)
+		assert.Equal(t, "file", e.Conflict)
+		assert.Equal(t, 409, e.Err.StatusCode)
+	})
+}
+
+func TestFiles_DownloadToFile(t *testing.T) {
+	hash, _ := ContentHash(bytes.NewBufferString("hello"))
+	body := "hello"
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Dropbox-API-Result", `{".tag": "file", "path_lower": "/a.txt", "content_hash": "`+hash+`"}`)
+		w.Write([]byte(body))
+	})
+
+	dir, err := ioutil.TempDir("", "dropbox")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	local := filepath.Join(dir, "a.txt")
+	out, err := c.Files.DownloadToFile("/a.txt", local)
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt", out.PathLower)
+
+	b, err := ioutil.ReadFile(local)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	body = "corrupt"
+	_, err = c.Files.DownloadToFile("/a.txt", local)
+	assert.Equal(t, ErrContentHashMismatch, err)
+
+	b, err = ioutil.ReadFile(local)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b), "existing file is untouched")
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1, "temporary file is removed")
+}
+
+func TestFiles_ListFolderLongpoll_reset(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, 409, "reset/..")
+	})
+
+	_, err := c.Files.ListFolderLongpoll(&ListFolderLongpollInput{Cursor: "cursor"})
+	assert.Equal(t, ErrCursorReset, err)
+
+	_, err = c.Files.ListFolderContinue(&ListFolderContinueInput{Cursor: "cursor"})
+	assert.Equal(t, ErrCursorReset, err)
+}
+
+func TestFiles_UploadWith(t *testing.T) {
+	var written int64
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.JSONEq(t, `{
+			"path": "/a.txt",
+			"mode": "overwrite",
+			"autorename": true,
+			"mute": true,
+			"client_modified": "2026-05-01T10:00:00Z"
+		}`, r.Header.Get("Dropbox-API-Arg"))
+		ioutil.ReadAll(r.Body)
+		writeJSON(w, 200, map[string]string{".tag": "file", "name": "a.txt", "path_lower": "/a.txt"})
+	})
+
+	out, err := c.Files.UploadWith("/a.txt", bytes.NewBufferString("hello"),
+		WithMode(WriteModeOverwrite),
+		WithAutoRename(),
+		WithMute(),
+		WithClientModified(time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)),
+		WithProgress(func(n, total int64) { written = n }))
+	assert.NoError(t, err)
+	assert.Equal(t, "/a.txt", out.PathLower)
+	assert.False(t, out.Renamed)
+	assert.Equal(t, int64(5), written)
+}
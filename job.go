@@ -0,0 +1,80 @@
+package dropbox
+
+import (
+	"context"
+	"time"
+)
+
+// LaunchResult is returned by SaveURL and the batch methods. Tag is
+// "complete" when the job completed inline, or "async_job_id" when it must
+// be polled with the AsyncJobID.
+type LaunchResult struct {
+	Tag        string `json:".tag"`
+	AsyncJobID string `json:"async_job_id,omitempty"`
+}
+
+// Async returns true if the job must be polled.
+func (r *LaunchResult) Async() bool {
+	return r.Tag == "async_job_id"
+}
+
+// Job returns the asynchronous job to poll.
+func (r *LaunchResult) Job() *AsyncJobID {
+	return &AsyncJobID{r.AsyncJobID}
+}
+
+// AsyncJobID identifies an asynchronous job, and is the input of each of the
+// CheckJobStatus methods.
+type AsyncJobID struct {
+	AsyncJobID string `json:"async_job_id"`
+}
+
+// PollOptions control how an asynchronous job is polled.
+type PollOptions struct {
+	// Interval between checks, defaulting to one second.
+	Interval time.Duration
+
+	// Timeout after which polling stops with ErrJobTimeout, or zero to poll
+	// until the client's context is done.
+	Timeout time.Duration
+}
+
+// PollJob calls check every interval until it reports the job is done or
+// returns an error, such as for a "failed" job. The check is typically a
+// call to one of the CheckJobStatus methods, mapping "in_progress" to false.
+func (c *Client) PollJob(check func() (done bool, err error), opts *PollOptions) error {
+	return pollJob(c.context(), opts, check)
+}
+
+// pollJob polls check as PollJob does, until ctx is done.
+func pollJob(ctx context.Context, opts *PollOptions, check func() (bool, error)) error {
+	interval := time.Second
+	var expired <-chan time.Time
+
+	if opts != nil {
+		if opts.Interval > 0 {
+			interval = opts.Interval
+		}
+
+		if opts.Timeout > 0 {
+			t := time.NewTimer(opts.Timeout)
+			defer t.Stop()
+			expired = t.C
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-expired:
+			return ErrJobTimeout
+		case <-time.After(interval):
+		}
+
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package dropbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_PollJob(t *testing.T) {
+	c := New(NewConfig("token"))
+
+	calls := 0
+	err := c.PollJob(func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	}, &PollOptions{Interval: time.Millisecond})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	failed := errors.New("failed")
+	err = c.PollJob(func() (bool, error) {
+		return false, failed
+	}, &PollOptions{Interval: time.Millisecond})
+	assert.Equal(t, failed, err)
+}
+
+func TestClient_PollJob_timeout(t *testing.T) {
+	c := New(NewConfig("token"))
+
+	err := c.PollJob(func() (bool, error) {
+		return false, nil
+	}, &PollOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond})
+	assert.Equal(t, ErrJobTimeout, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = c.WithContext(ctx).PollJob(func() (bool, error) {
+		return false, nil
+	}, nil)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestLaunchResult(t *testing.T) {
+	async := `{".tag": "async_job_id", "async_job_id": "34g93hh34h04y384084"}`
+
+	t.Run("save_url", func(t *testing.T) {
+		var out SaveURLOutput
+		assert.NoError(t, json.Unmarshal([]byte(async), &out))
+		assert.True(t, out.Async())
+		assert.Equal(t, &AsyncJobID{"34g93hh34h04y384084"}, out.Job())
+
+		out = SaveURLOutput{}
+		assert.NoError(t, json.Unmarshal([]byte(`{".tag": "complete", "name": "a.txt", "path_lower": "/a.txt", "size": 5}`), &out))
+		assert.False(t, out.Async())
+		assert.Equal(t, "complete", out.LaunchResult.Tag)
+		assert.Equal(t, "/a.txt", out.PathLower)
+		assert.Equal(t, uint64(5), out.Size)
+	})
+
+	t.Run("copy_batch", func(t *testing.T) {
+		var out CopyBatchOutput
+		assert.NoError(t, json.Unmarshal([]byte(`{".tag": "complete", "entries": [{".tag": "success", "success": {".tag": "file", "path_lower": "/b.txt"}}]}`), &out))
+		assert.False(t, out.Async())
+		assert.Equal(t, "/b.txt", out.Entries[0].Success.PathLower)
+	})
+
+	t.Run("move_batch", func(t *testing.T) {
+		var out MoveBatchOutput
+		assert.NoError(t, json.Unmarshal([]byte(async), &out))
+		assert.Equal(t, &AsyncJobID{"34g93hh34h04y384084"}, out.Job())
+	})
+
+	t.Run("delete_batch", func(t *testing.T) {
+		var out DeleteBatchOutput
+		assert.NoError(t, json.Unmarshal([]byte(`{".tag": "complete", "entries": [{".tag": "success", "metadata": {".tag": "file", "path_lower": "/a.txt"}}]}`), &out))
+		assert.False(t, out.Async())
+		assert.Equal(t, "/a.txt", out.Entries[0].Metadata.PathLower)
+	})
+
+	t.Run("create_folder_batch", func(t *testing.T) {
+		var out CreateFolderBatchOutput
+		assert.NoError(t, json.Unmarshal([]byte(async), &out))
+		assert.True(t, out.Async())
+
+		b, err := json.Marshal(out.Job())
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"async_job_id": "34g93hh34h04y384084"}`, string(b))
+	})
+}
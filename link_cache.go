@@ -0,0 +1,125 @@
+package dropbox
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLinkCacheTTL is how long temporary links are cached by default,
+// leaving half an hour of their four hour lifetime.
+const defaultLinkCacheTTL = 3*time.Hour + 30*time.Minute
+
+// TemporaryLinkCache caches the temporary links returned by GetTemporaryLink
+// by path and rev, for serving the same files repeatedly. It is safe for
+// concurrent use.
+type TemporaryLinkCache struct {
+	Files *Files
+
+	// TTL is how long a link is returned from the cache, which must be less
+	// than the four hours it is valid for. Defaults to three and a half hours.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	links map[linkCacheKey]*cachedLink
+}
+
+// linkCacheKey identifies a revision of a file.
+type linkCacheKey struct {
+	path string
+	rev  string
+}
+
+// cachedLink is a temporary link and when it is no longer returned.
+type cachedLink struct {
+	out     *GetTemporaryLinkOutput
+	expires time.Time
+}
+
+// NewTemporaryLinkCache returns a cache requesting links with files.
+func NewTemporaryLinkCache(files *Files) *TemporaryLinkCache {
+	return &TemporaryLinkCache{Files: files}
+}
+
+// Get returns a temporary link to the file at path, which is cached while the
+// file is at rev. An empty rev always requests a new link, which is cached
+// under the file's current rev.
+func (c *TemporaryLinkCache) Get(path, rev string) (*GetTemporaryLinkOutput, error) {
+	path = linkCachePath(path)
+
+	if rev != "" {
+		if out := c.lookup(linkCacheKey{path, rev}); out != nil {
+			return out, nil
+		}
+	}
+
+	out, err := c.Files.GetTemporaryLink(&GetTemporaryLinkInput{Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = defaultLinkCacheTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.links == nil {
+		c.links = make(map[linkCacheKey]*cachedLink)
+	}
+	c.links[linkCacheKey{path, out.Metadata.Rev}] = &cachedLink{out, time.Now().Add(ttl)}
+
+	return out, nil
+}
+
+// lookup returns the cached link for key, or nil if it is missing or expired.
+func (c *TemporaryLinkCache) lookup(key linkCacheKey) *GetTemporaryLinkOutput {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	link, ok := c.links[key]
+	if !ok {
+		return nil
+	}
+
+	if time.Now().After(link.expires) {
+		delete(c.links, key)
+		return nil
+	}
+
+	return link.out
+}
+
+// Remove the cached links to every revision of the file at path.
+func (c *TemporaryLinkCache) Remove(path string) {
+	path = linkCachePath(path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.links {
+		if key.path == path {
+			delete(c.links, key)
+		}
+	}
+}
+
+// linkCachePath returns the key of path, ignoring case as Dropbox does. IDs
+// and other non-path identifiers are left as is.
+func linkCachePath(path string) string {
+	path = normalizePath(path)
+	if strings.HasPrefix(path, "/") {
+		return strings.ToLower(path)
+	}
+	return path
+}
+
+// Clear removes every cached link.
+func (c *TemporaryLinkCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.links = nil
+}
@@ -0,0 +1,49 @@
+package dropbox
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemporaryLinkCache(t *testing.T) {
+	requests := 0
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		writeJSON(w, 200, map[string]interface{}{
+			"metadata": map[string]string{"path_lower": "/a.mp4", "rev": "a1"},
+			"link":     "https://dl.dropboxusercontent.com/apitl/1/" + string(rune('0'+requests)),
+		})
+	})
+
+	cache := NewTemporaryLinkCache(c.Files)
+
+	out, err := cache.Get("/A.mp4", "a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://dl.dropboxusercontent.com/apitl/1/1", out.Link)
+
+	out, err = cache.Get("/a.mp4", "a1")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://dl.dropboxusercontent.com/apitl/1/1", out.Link)
+	assert.Equal(t, 1, requests)
+
+	_, err = cache.Get("/a.mp4", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, requests, "empty rev is not cached")
+
+	cache.Remove("/a.mp4")
+	_, err = cache.Get("/a.mp4", "a1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, requests)
+
+	cache.Clear()
+	cache.TTL = time.Nanosecond
+	_, err = cache.Get("/a.mp4", "a1")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = cache.Get("/a.mp4", "a1")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, requests, "expired links are requested again")
+}
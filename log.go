@@ -0,0 +1,103 @@
+package dropbox
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestLog describes a single attempt of a request and its response, as
+// passed to Config.OnRequest.
+type RequestLog struct {
+	Method string
+	URL    string
+
+	// Header of the request, with the access token redacted. The arguments
+	// of content requests are in the Dropbox-API-Arg header.
+	Header http.Header
+
+	// Body of rpc requests. The file contents of content requests are not
+	// included.
+	Body []byte
+
+	// StatusCode and ResponseHeader are empty when Err is set.
+	StatusCode     int
+	ResponseHeader http.Header
+
+	// ResponseBody is the body of error responses.
+	ResponseBody []byte
+
+	Duration time.Duration
+	Err      error
+}
+
+// send performs a single attempt of req, passing a RequestLog of it to
+// OnRequest when set.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.OnRequest == nil {
+		return c.httpClient().Do(req)
+	}
+
+	log := &RequestLog{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: redact(req.Header),
+		Body:   requestBody(req),
+	}
+
+	start := time.Now()
+	res, err := c.httpClient().Do(req)
+	log.Duration = time.Since(start)
+
+	if err != nil {
+		log.Err = err
+		c.OnRequest(log)
+		return nil, err
+	}
+
+	log.StatusCode = res.StatusCode
+	log.ResponseHeader = res.Header
+
+	if res.StatusCode >= 400 {
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			log.Err = err
+			c.OnRequest(log)
+			return nil, err
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(b))
+		log.ResponseBody = b
+	}
+
+	c.OnRequest(log)
+	return res, nil
+}
+
+// redact returns a copy of h without the access token.
+func redact(h http.Header) http.Header {
+	h = h.Clone()
+	if h.Get("Authorization") != "" {
+		h.Set("Authorization", "Bearer REDACTED")
+	}
+	return h
+}
+
+// requestBody returns the body of a JSON request, which is read from a copy
+// so that req can still be sent.
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	b, _ := ioutil.ReadAll(body)
+	return b
+}
@@ -0,0 +1,177 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Paper client for Dropbox Paper docs.
+type Paper struct {
+	*Client
+}
+
+// NewPaper client.
+func NewPaper(config *Config) *Paper {
+	return &Paper{
+		Client: &Client{
+			Config: config,
+		},
+	}
+}
+
+// PaperDocFilter selects the docs listed by DocsList.
+type PaperDocFilter string
+
+// Paper doc filters supported.
+const (
+	PaperDocFilterAccessed PaperDocFilter = "docs_accessed"
+	PaperDocFilterCreated                 = "docs_created"
+)
+
+// PaperDocSort orders the docs listed by DocsList.
+type PaperDocSort string
+
+// Paper doc sorts supported.
+const (
+	PaperDocSortAccessed PaperDocSort = "accessed"
+	PaperDocSortModified              = "modified"
+	PaperDocSortCreated               = "created"
+)
+
+// PaperSortOrder is the direction of a sort.
+type PaperSortOrder string
+
+// Sort orders supported.
+const (
+	PaperSortOrderAscending  PaperSortOrder = "ascending"
+	PaperSortOrderDescending                = "descending"
+)
+
+// PaperExportFormat is the format a Paper doc is exported as.
+type PaperExportFormat string
+
+// Export formats supported.
+const (
+	PaperExportFormatMarkdown PaperExportFormat = "markdown"
+	PaperExportFormatHTML                       = "html"
+)
+
+// PaperDocsListInput request input. Limit defaults to 1000.
+type PaperDocsListInput struct {
+	FilterBy  PaperDocFilter `json:"filter_by,omitempty"`
+	SortBy    PaperDocSort   `json:"sort_by,omitempty"`
+	SortOrder PaperSortOrder `json:"sort_order,omitempty"`
+	Limit     int            `json:"limit,omitempty"`
+}
+
+// PaperCursor pages through the docs listed, until its Expiration.
+type PaperCursor struct {
+	Value      string     `json:"value"`
+	Expiration *time.Time `json:"expiration,omitempty"`
+}
+
+// PaperDocsListOutput request output. When HasMore is true the next page is
+// returned by DocsListContinue with the Cursor.
+type PaperDocsListOutput struct {
+	DocIDs  []string    `json:"doc_ids"`
+	Cursor  PaperCursor `json:"cursor"`
+	HasMore bool        `json:"has_more"`
+}
+
+// DocsList returns the IDs of the Paper docs of the user.
+func (c *Paper) DocsList(in *PaperDocsListInput) (out *PaperDocsListOutput, err error) {
+	body, err := c.call("/paper/docs/list", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// PaperDocsListContinueInput request input, with the Value of the Cursor.
+type PaperDocsListContinueInput struct {
+	Cursor string `json:"cursor"`
+}
+
+// DocsListContinue returns the next page of Paper docs, returning
+// ErrEmptyCursor when there is no cursor.
+func (c *Paper) DocsListContinue(in *PaperDocsListContinueInput) (out *PaperDocsListOutput, err error) {
+	if in.Cursor == "" {
+		return nil, ErrEmptyCursor
+	}
+
+	body, err := c.call("/paper/docs/list/continue", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// DocsListAll returns the IDs of all of the Paper docs of the user, following
+// the cursor until there are no more.
+func (c *Paper) DocsListAll(in *PaperDocsListInput) ([]string, error) {
+	out, err := c.DocsList(in)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := out.DocIDs
+
+	for out.HasMore {
+		out, err = c.DocsListContinue(&PaperDocsListContinueInput{out.Cursor.Value})
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, out.DocIDs...)
+	}
+
+	return ids, nil
+}
+
+// PaperDocsDownloadInput request input. ExportFormat defaults to markdown.
+type PaperDocsDownloadInput struct {
+	DocID        string            `json:"doc_id"`
+	ExportFormat PaperExportFormat `json:"export_format"`
+}
+
+// PaperDocsDownloadOutput request output. The Body must be closed.
+type PaperDocsDownloadOutput struct {
+	Body   io.ReadCloser `json:"-"`
+	Length int64         `json:"-"`
+
+	Owner    string `json:"owner"`
+	Title    string `json:"title"`
+	Revision int64  `json:"revision"`
+	MimeType string `json:"mime_type"`
+}
+
+// DocsDownload exports a Paper doc as markdown or HTML.
+func (c *Paper) DocsDownload(in *PaperDocsDownloadInput) (out *PaperDocsDownloadOutput, err error) {
+	if in.ExportFormat == "" {
+		download := *in
+		download.ExportFormat = PaperExportFormatMarkdown
+		in = &download
+	}
+
+	res, err := c.apiContent("/paper/docs/download", in)
+	if err != nil {
+		return
+	}
+
+	out = &PaperDocsDownloadOutput{}
+	if err = json.Unmarshal([]byte(res.Header.Get("Dropbox-API-Result")), out); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	out.Body = res.Body
+	out.Length = res.ContentLength
+	return
+}
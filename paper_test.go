@@ -0,0 +1,68 @@
+package dropbox
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaper_DocsListAll(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/2/paper/docs/list":
+			assert.JSONEq(t, `{"filter_by": "docs_created", "sort_by": "modified", "limit": 1}`, string(b))
+			writeJSON(w, 200, map[string]interface{}{
+				"doc_ids":  []string{"a"},
+				"cursor":   map[string]string{"value": "cursor", "expiration": "2026-11-01T00:00:00Z"},
+				"has_more": true,
+			})
+		case "/2/paper/docs/list/continue":
+			assert.JSONEq(t, `{"cursor": "cursor"}`, string(b))
+			writeJSON(w, 200, map[string]interface{}{
+				"doc_ids":  []string{"b"},
+				"cursor":   map[string]string{"value": "done"},
+				"has_more": false,
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	ids, err := c.Paper.DocsListAll(&PaperDocsListInput{
+		FilterBy: PaperDocFilterCreated,
+		SortBy:   PaperDocSortModified,
+		Limit:    1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids)
+
+	_, err = c.Paper.DocsListContinue(&PaperDocsListContinueInput{})
+	assert.Equal(t, ErrEmptyCursor, err)
+}
+
+func TestPaper_DocsDownload(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/paper/docs/download", r.URL.Path)
+		assert.JSONEq(t, `{"doc_id": "uaSvRuxvnkFa12PTkBv5q", "export_format": "markdown"}`, r.Header.Get("Dropbox-API-Arg"))
+
+		w.Header().Set("Dropbox-API-Result", `{"owner": "james@example.com", "title": "Café notes", "revision": 456736745, "mime_type": "text/x-markdown"}`)
+		w.Write([]byte("# Café notes"))
+	})
+
+	out, err := c.Paper.DocsDownload(&PaperDocsDownloadInput{DocID: "uaSvRuxvnkFa12PTkBv5q"})
+	assert.NoError(t, err)
+	defer out.Body.Close()
+
+	assert.Equal(t, "Café notes", out.Title)
+	assert.Equal(t, int64(456736745), out.Revision)
+	assert.Equal(t, "text/x-markdown", out.MimeType)
+
+	b, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "# Café notes", string(b))
+}
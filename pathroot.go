@@ -0,0 +1,98 @@
+package dropbox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathRoot selects the namespace which paths are relative to, sent as the
+// Dropbox-API-Path-Root header. Use PathRootHome, PathRootRoot or
+// PathRootNamespace to construct one.
+type PathRoot struct {
+	Tag         string `json:".tag"`
+	Root        string `json:"root,omitempty"`
+	NamespaceID string `json:"namespace_id,omitempty"`
+}
+
+// PathRootHome makes paths relative to the user's home namespace, which is
+// the default.
+func PathRootHome() *PathRoot {
+	return &PathRoot{Tag: "home"}
+}
+
+// PathRootRoot makes paths relative to the user's root namespace, such as a
+// team space, failing requests if it is no longer the given namespace ID.
+func PathRootRoot(namespaceID string) *PathRoot {
+	return &PathRoot{Tag: "root", Root: namespaceID}
+}
+
+// PathRootNamespace makes paths relative to the given namespace, such as a
+// shared folder or team folder the user has access to.
+func PathRootNamespace(namespaceID string) *PathRoot {
+	return &PathRoot{Tag: "namespace_id", NamespaceID: namespaceID}
+}
+
+// namespace returns the namespace ID selected, or "" for the home namespace.
+func (r *PathRoot) namespace() string {
+	if r == nil {
+		return ""
+	}
+
+	switch r.Tag {
+	case "root":
+		return r.Root
+	case "namespace_id":
+		return r.NamespaceID
+	default:
+		return ""
+	}
+}
+
+// String returns a description of the namespace selected.
+func (r *PathRoot) String() string {
+	if ns := r.namespace(); ns != "" {
+		return fmt.Sprintf("%s %s", r.Tag, ns)
+	}
+	return "home"
+}
+
+// PathRootError is returned by CheckPath when a path appears to be relative
+// to a different namespace than the one selected by the Config's PathRoot.
+type PathRootError struct {
+	Path     string
+	PathRoot *PathRoot
+}
+
+// Error string.
+func (e *PathRootError) Error() string {
+	return fmt.Sprintf("dropbox: path %q does not match the %s path root", e.Path, e.PathRoot)
+}
+
+// Root returns a description of the namespace paths are relative to.
+func (c *Config) Root() string {
+	return c.PathRoot.String()
+}
+
+// CheckPath returns a *PathRootError when p is namespace-relative ("ns:<id>/...")
+// and names a different namespace than the PathRoot, or when the PathRoot is
+// the home namespace and p begins with the HomePath, meaning it is relative
+// to the team's root namespace instead.
+func (c *Config) CheckPath(p string) error {
+	ns := c.PathRoot.namespace()
+
+	if ns == "" && c.HomePath != "" {
+		home := strings.ToLower(c.HomePath)
+		if lower := strings.ToLower(p); lower == home || strings.HasPrefix(lower, home+"/") {
+			return &PathRootError{p, PathRootHome()}
+		}
+	}
+
+	if ns != "" && strings.HasPrefix(p, "ns:") {
+		id := strings.SplitN(strings.TrimPrefix(p, "ns:"), "/", 2)[0]
+		if id != ns {
+			return &PathRootError{p, c.PathRoot}
+		}
+	}
+
+	return nil
+}
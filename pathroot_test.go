@@ -0,0 +1,65 @@
+package dropbox
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_CheckPath(t *testing.T) {
+	c := NewConfig("token")
+	c.HomePath = "/Franz Ferdinand (Personal)"
+
+	assert.Equal(t, "home", c.Root())
+	assert.NoError(t, c.CheckPath("/Photos/a.jpg"))
+	assert.NoError(t, c.CheckPath("ns:1234/a.jpg"))
+	assert.IsType(t, &PathRootError{}, c.CheckPath("/franz ferdinand (personal)/Photos/a.jpg"))
+
+	c.PathRoot = PathRootRoot("1234")
+	assert.Equal(t, "root 1234", c.Root())
+	assert.NoError(t, c.CheckPath("/Franz Ferdinand (Personal)/Photos/a.jpg"))
+	assert.NoError(t, c.CheckPath("ns:1234/a.jpg"))
+
+	err := c.CheckPath("ns:5678/a.jpg")
+	assert.IsType(t, &PathRootError{}, err)
+	assert.Equal(t, `dropbox: path "ns:5678/a.jpg" does not match the root 1234 path root`, err.Error())
+}
+
+func TestConfig_PathRoot_header(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `{".tag":"namespace_id","namespace_id":"1234"}`, r.Header.Get("Dropbox-API-Path-Root"))
+		writeJSON(w, 200, map[string]string{".tag": "folder"})
+	})
+	c.PathRoot = PathRootNamespace("1234")
+
+	_, err := c.Files.GetMetadata(&GetMetadataInput{Path: "/Shared"})
+	assert.NoError(t, err)
+}
+
+func TestClient_WithPathRoot(t *testing.T) {
+	var roots []string
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		roots = append(roots, r.Header.Get("Dropbox-API-Path-Root"))
+		writeJSON(w, 200, map[string]string{".tag": "folder"})
+	})
+
+	team := c.WithPathRoot(PathRootRoot("1234"))
+
+	_, err := team.Files.GetMetadata(&GetMetadataInput{Path: "/Team"})
+	assert.NoError(t, err)
+
+	_, err = team.WithContext(context.Background()).Files.GetMetadata(&GetMetadataInput{Path: "/Team"})
+	assert.NoError(t, err)
+
+	_, err = c.Files.GetMetadata(&GetMetadataInput{Path: "/Home"})
+	assert.NoError(t, err)
+
+	team.Config.PathRoot = PathRootHome()
+	_, err = team.Files.GetMetadata(&GetMetadataInput{Path: "/Team"})
+	assert.NoError(t, err)
+
+	root := `{".tag":"root","root":"1234"}`
+	assert.Equal(t, []string{root, root, "", root}, roots)
+}
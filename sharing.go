@@ -1,7 +1,9 @@
 package dropbox
 
 import (
-	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
 	"time"
 )
 
@@ -19,19 +21,47 @@ func NewSharing(config *Config) *Sharing {
 	}
 }
 
+// SharedLinkSettings are the settings applied when creating a shared link.
+// Audience and Access supersede RequestedVisibility, and AllowDownload is
+// left to the account's default when nil.
+type SharedLinkSettings struct {
+	RequestedVisibility VisibilityType  `json:"requested_visibility,omitempty"`
+	LinkPassword        string          `json:"link_password,omitempty"`
+	Expires             string          `json:"expires,omitempty"`
+	Audience            LinkAudience    `json:"audience,omitempty"`
+	Access              LinkAccessLevel `json:"access,omitempty"`
+	AllowDownload       *bool           `json:"allow_download,omitempty"`
+}
+
+// LinkAudience determines who can use a shared link.
+type LinkAudience string
+
+// Link audiences supported.
+const (
+	LinkAudiencePublic  LinkAudience = "public"
+	LinkAudienceTeam                 = "team"
+	LinkAudienceNoOne                = "no_one"
+	LinkAudienceMembers              = "members"
+)
+
+// LinkAccessLevel is the access a shared link grants.
+type LinkAccessLevel string
+
+// Link access levels supported.
+const (
+	LinkAccessViewer LinkAccessLevel = "viewer"
+	LinkAccessEditor                 = "editor"
+)
+
 // CreateSharedLinkInput request input.
 type CreateSharedLinkInput struct {
-	Path string `json:"path"`
+	Path     string              `json:"path"`
+	Settings *SharedLinkSettings `json:"settings,omitempty"`
 }
 
 // CreateSharedLinkOutput request output.
 type CreateSharedLinkOutput struct {
-	URL             string `json:"url"`
-	Path            string `json:"path"`
-	VisibilityModel struct {
-		Tag VisibilityType `json:".tag"`
-	} `json:"visibility"`
-	Expires time.Time `json:"expires,omitempty"`
+	SharedLinkOutput
 }
 
 // VisibilityType determines who can access the link.
@@ -54,19 +84,26 @@ func (c *Sharing) CreateSharedLink(in *CreateSharedLinkInput) (out *CreateShared
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
-// ListShareLinksInput request input.
+// ListShareLinksInput request input. When Path is set only links giving
+// access to it are returned, and DirectOnly excludes links to its parent folders.
 type ListShareLinksInput struct {
-	Path string `json:"path"`
+	Path       string `json:"path,omitempty"`
+	Cursor     string `json:"cursor,omitempty"`
+	DirectOnly bool   `json:"direct_only,omitempty"`
 }
 
 // SharedLinkOutput request output.
 type SharedLinkOutput struct {
+	Tag             string `json:".tag"`
 	URL             string `json:"url"`
+	Name            string `json:"name"`
+	ID              string `json:"id"`
 	Path            string `json:"path"`
+	PathLower       string `json:"path_lower"`
 	VisibilityModel struct {
 		Tag VisibilityType `json:".tag"`
 	} `json:"visibility"`
@@ -75,7 +112,9 @@ type SharedLinkOutput struct {
 
 // ListShareLinksOutput request output.
 type ListShareLinksOutput struct {
-	Links []SharedLinkOutput `json:"links"`
+	Links   []SharedLinkOutput `json:"links"`
+	HasMore bool               `json:"has_more"`
+	Cursor  string             `json:"cursor"`
 }
 
 // ListSharedLinks gets shared links of input.
@@ -87,10 +126,112 @@ func (c *Sharing) ListSharedLinks(in *ListShareLinksInput) (out *ListShareLinksO
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
+	return
+}
+
+// RevokeSharedLinkInput request input.
+type RevokeSharedLinkInput struct {
+	URL string `json:"url"`
+}
+
+// RevokeSharedLink revokes a shared link, returning ErrSharedLinkNotFound if
+// it does not exist.
+func (c *Sharing) RevokeSharedLink(in *RevokeSharedLinkInput) error {
+	err := discard(c.call("/sharing/revoke_shared_link", in))
+	if hasTag(err, "shared_link_not_found") {
+		return ErrSharedLinkNotFound
+	}
+	return err
+}
+
+// GetSharedLinkFileInput request input. Path is the file within a shared
+// folder link, and LinkPassword is required for password protected links.
+type GetSharedLinkFileInput struct {
+	URL          string `json:"url"`
+	Path         string `json:"path,omitempty"`
+	LinkPassword string `json:"link_password,omitempty"`
+}
+
+// SharedLinkFileMetadata describes the file of a shared link.
+type SharedLinkFileMetadata struct {
+	SharedLinkOutput
+	ClientModified time.Time `json:"client_modified"`
+	ServerModified time.Time `json:"server_modified"`
+	Rev            string    `json:"rev"`
+	Size           uint64    `json:"size"`
+}
+
+// GetSharedLinkFileOutput request output.
+type GetSharedLinkFileOutput struct {
+	Body     io.ReadCloser
+	Length   int64
+	Metadata SharedLinkFileMetadata
+}
+
+// GetSharedLinkFile downloads the file of a shared link, which need not be
+// owned by the user.
+func (c *Sharing) GetSharedLinkFile(in *GetSharedLinkFileInput) (out *GetSharedLinkFileOutput, err error) {
+	out = &GetSharedLinkFileOutput{}
+
+	body, l, err := c.downloadResult("/sharing/get_shared_link_file", in, nil, &out.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Body = body
+	out.Length = l
 	return
 }
 
+// GetOrCreateSharedLink returns the existing shared link for path, creating
+// one with the given settings when there is none. The settings are not
+// applied to an existing link.
+func (c *Sharing) GetOrCreateSharedLink(path string, settings SharedLinkSettings) (*SharedLinkOutput, error) {
+	link, err := c.directSharedLink(path)
+	if err != nil || link != nil {
+		return link, err
+	}
+
+	out, err := c.CreateSharedLink(&CreateSharedLinkInput{
+		Path:     path,
+		Settings: &settings,
+	})
+
+	// another caller may have created the link since it was listed
+	if hasTag(err, "shared_link_already_exists") {
+		if link, err := c.directSharedLink(path); err != nil || link != nil {
+			return link, err
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &out.SharedLinkOutput, nil
+}
+
+// directSharedLink returns the shared link to path itself, ignoring links
+// to its parent folders, or nil when there is none.
+func (c *Sharing) directSharedLink(path string) (*SharedLinkOutput, error) {
+	out, err := c.ListSharedLinks(&ListShareLinksInput{
+		Path:       path,
+		DirectOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, link := range out.Links {
+		if link.ID == path || link.PathLower == strings.ToLower(path) {
+			return &out.Links[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
 // ListSharedFolderInput request input.
 type ListSharedFolderInput struct {
 	Limit   uint64         `json:"limit"`
@@ -116,7 +257,7 @@ func (c *Sharing) ListSharedFolders(in *ListSharedFolderInput) (out *ListSharedF
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
@@ -133,10 +274,50 @@ func (c *Sharing) ListSharedFoldersContinue(in *ListSharedFolderContinueInput) (
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
+// GetSharedFolderMetadataInput request input.
+type GetSharedFolderMetadataInput struct {
+	SharedFolderID string `json:"shared_folder_id"`
+}
+
+// GetSharedFolderMetadata returns the metadata of a shared folder.
+func (c *Sharing) GetSharedFolderMetadata(in *GetSharedFolderMetadataInput) (out *SharedFolderMetadata, err error) {
+	body, err := c.call("/sharing/get_folder_metadata", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListSharedFolderContents returns the entries of the shared folder with the
+// given ID. The folder is listed by its path when it is mounted in the user's
+// Dropbox, otherwise by its namespace.
+func (c *Sharing) ListSharedFolderContents(sharedFolderID string) ([]*Metadata, error) {
+	folder, err := c.GetSharedFolderMetadata(&GetSharedFolderMetadataInput{sharedFolderID})
+	if err != nil {
+		return nil, err
+	}
+
+	path := folder.PathLower
+	if path == "" {
+		path = "ns:" + sharedFolderID
+	}
+
+	files := &Files{c.Client}
+	entries, err := files.ListFolderAll(&ListFolderInput{Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
 // SharedFolderMetadata includes basic information about the shared folder.
 type SharedFolderMetadata struct {
 	AccessType struct {
@@ -209,3 +390,368 @@ const (
 	MemberPolicyTeam   MemberPolicy = "team"
 	MemberPolicyAnyone              = "anyone"
 )
+
+// UserInfo is basic information about a user.
+type UserInfo struct {
+	AccountID    string `json:"account_id"`
+	Email        string `json:"email"`
+	DisplayName  string `json:"display_name"`
+	SameTeam     bool   `json:"same_team"`
+	TeamMemberID string `json:"team_member_id,omitempty"`
+}
+
+// GroupInfo is basic information about a group.
+type GroupInfo struct {
+	GroupName   string `json:"group_name"`
+	GroupID     string `json:"group_id"`
+	MemberCount uint64 `json:"member_count"`
+	IsMember    bool   `json:"is_member"`
+	IsOwner     bool   `json:"is_owner"`
+	SameTeam    bool   `json:"same_team"`
+}
+
+// InviteeInfo is information about an invited member who has not yet joined.
+type InviteeInfo struct {
+	Tag   string `json:".tag"`
+	Email string `json:"email"`
+}
+
+// UserMembershipInfo is the membership of a user.
+type UserMembershipInfo struct {
+	AccessType struct {
+		Tag AccessType `json:".tag"`
+	} `json:"access_type"`
+	User        UserInfo `json:"user"`
+	IsInherited bool     `json:"is_inherited"`
+}
+
+// GroupMembershipInfo is the membership of a group.
+type GroupMembershipInfo struct {
+	AccessType struct {
+		Tag AccessType `json:".tag"`
+	} `json:"access_type"`
+	Group       GroupInfo `json:"group"`
+	IsInherited bool      `json:"is_inherited"`
+}
+
+// InviteeMembershipInfo is the membership of an invitee.
+type InviteeMembershipInfo struct {
+	AccessType struct {
+		Tag AccessType `json:".tag"`
+	} `json:"access_type"`
+	Invitee     InviteeInfo `json:"invitee"`
+	IsInherited bool        `json:"is_inherited"`
+}
+
+// ListFileMembersInput request input. Set IncludeInherited to also
+// return members who have access through a parent shared folder.
+type ListFileMembersInput struct {
+	File             string `json:"file"`
+	IncludeInherited bool   `json:"include_inherited"`
+	Limit            uint64 `json:"limit,omitempty"`
+}
+
+// ListFileMembersOutput lists the members of a file with a cursor to retrieve the next page.
+type ListFileMembersOutput struct {
+	Users    []UserMembershipInfo    `json:"users"`
+	Groups   []GroupMembershipInfo   `json:"groups"`
+	Invitees []InviteeMembershipInfo `json:"invitees"`
+	Cursor   string                  `json:"cursor"`
+}
+
+// ListFileMembers returns the users, groups and invitees who have access to a file.
+func (c *Sharing) ListFileMembers(in *ListFileMembersInput) (out *ListFileMembersOutput, err error) {
+	body, err := c.call("/sharing/list_file_members", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFileMembersContinueInput request input.
+type ListFileMembersContinueInput struct {
+	Cursor string `json:"cursor"`
+}
+
+// ListFileMembersContinue paginates using the cursor from ListFileMembers,
+// returning ErrEmptyCursor when there is no cursor.
+func (c *Sharing) ListFileMembersContinue(in *ListFileMembersContinueInput) (out *ListFileMembersOutput, err error) {
+	if in.Cursor == "" {
+		return nil, ErrEmptyCursor
+	}
+
+	body, err := c.call("/sharing/list_file_members/continue", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// MemberSelectorType determines how a member is identified.
+type MemberSelectorType string
+
+// Member selector types supported.
+const (
+	MemberSelectorDropboxID MemberSelectorType = "dropbox_id"
+	MemberSelectorEmail     MemberSelectorType = "email"
+)
+
+// MemberSelector identifies a member by Dropbox ID or email address.
+type MemberSelector struct {
+	Tag       MemberSelectorType `json:".tag"`
+	DropboxID string             `json:"dropbox_id,omitempty"`
+	Email     string             `json:"email,omitempty"`
+}
+
+// FileMemberActionResult is the result of an action on a file member.
+type FileMemberActionResult struct {
+	Member MemberSelector `json:"member"`
+	Result struct {
+		Tag         string `json:".tag"`
+		MemberError struct {
+			Tag string `json:".tag"`
+		} `json:"member_error"`
+	} `json:"result"`
+}
+
+// AddFileMemberInput request input.
+type AddFileMemberInput struct {
+	File          string           `json:"file"`
+	Members       []MemberSelector `json:"members"`
+	CustomMessage string           `json:"custom_message,omitempty"`
+	Quiet         bool             `json:"quiet"`
+	AccessLevel   AccessType       `json:"access_level,omitempty"`
+}
+
+// AddFileMemberOutput request output.
+type AddFileMemberOutput struct {
+	Results []FileMemberActionResult
+}
+
+// AddFileMember adds members to a file, returning the result for each member.
+func (c *Sharing) AddFileMember(in *AddFileMemberInput) (out *AddFileMemberOutput, err error) {
+	body, err := c.call("/sharing/add_file_member", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	out = &AddFileMemberOutput{}
+	err = decode(body, &out.Results)
+	return
+}
+
+// RemoveFileMemberInput request input.
+type RemoveFileMemberInput struct {
+	File   string         `json:"file"`
+	Member MemberSelector `json:"member"`
+}
+
+// RemoveFileMemberOutput request output.
+type RemoveFileMemberOutput struct {
+	Tag         string `json:".tag"`
+	MemberError struct {
+		Tag string `json:".tag"`
+	} `json:"member_error"`
+}
+
+// RemoveFileMember removes a member from a file.
+func (c *Sharing) RemoveFileMember(in *RemoveFileMemberInput) (out *RemoveFileMemberOutput, err error) {
+	body, err := c.call("/sharing/remove_file_member_2", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFolderMembersInput request input.
+type ListFolderMembersInput struct {
+	SharedFolderID string `json:"shared_folder_id"`
+	Limit          uint64 `json:"limit,omitempty"`
+}
+
+// ListFolderMembersOutput lists the members of a shared folder with a cursor
+// to retrieve the next page.
+type ListFolderMembersOutput struct {
+	Users    []UserMembershipInfo    `json:"users"`
+	Groups   []GroupMembershipInfo   `json:"groups"`
+	Invitees []InviteeMembershipInfo `json:"invitees"`
+	Cursor   string                  `json:"cursor"`
+}
+
+// ListFolderMembers returns the users, groups and invitees who are members of
+// a shared folder.
+func (c *Sharing) ListFolderMembers(in *ListFolderMembersInput) (out *ListFolderMembersOutput, err error) {
+	body, err := c.call("/sharing/list_folder_members", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// ListFolderMembersContinueInput request input.
+type ListFolderMembersContinueInput struct {
+	Cursor string `json:"cursor"`
+}
+
+// ListFolderMembersContinue returns the next page of members, returning
+// ErrEmptyCursor when there is no cursor.
+func (c *Sharing) ListFolderMembersContinue(in *ListFolderMembersContinueInput) (out *ListFolderMembersOutput, err error) {
+	if in.Cursor == "" {
+		return nil, ErrEmptyCursor
+	}
+
+	body, err := c.call("/sharing/list_folder_members/continue", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// SharingJobStatus is the status of an asynchronous sharing job. Tag is
+// "in_progress", "complete", or "failed" with the reason.
+type SharingJobStatus struct {
+	Tag    string `json:".tag"`
+	Failed *struct {
+		Tag string `json:".tag"`
+	} `json:"failed,omitempty"`
+}
+
+// done returns whether the job completed, or an error if it failed.
+func (s *SharingJobStatus) done(op string) (bool, error) {
+	switch s.Tag {
+	case "in_progress":
+		return false, nil
+	case "complete":
+		return true, nil
+	case "failed":
+		if s.Failed != nil {
+			return false, fmt.Errorf("dropbox: %s: %s", op, s.Failed.Tag)
+		}
+	}
+	return false, fmt.Errorf("dropbox: %s: %s", op, s.Tag)
+}
+
+// RemoveFolderMemberInput request input. Set LeaveACopy for the member to
+// keep a copy of the folder's contents.
+type RemoveFolderMemberInput struct {
+	SharedFolderID string         `json:"shared_folder_id"`
+	Member         MemberSelector `json:"member"`
+	LeaveACopy     bool           `json:"leave_a_copy"`
+}
+
+// RemoveFolderMember removes a member from a shared folder. The removal is an
+// asynchronous job polled with CheckRemoveMemberJobStatus, or use
+// RemoveFolderMemberWait.
+func (c *Sharing) RemoveFolderMember(in *RemoveFolderMemberInput) (out *LaunchResult, err error) {
+	body, err := c.call("/sharing/remove_folder_member", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// CheckRemoveMemberJobStatus returns the status of a RemoveFolderMember job.
+func (c *Sharing) CheckRemoveMemberJobStatus(in *AsyncJobID) (out *SharingJobStatus, err error) {
+	body, err := c.call("/sharing/check_remove_member_job_status", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// RemoveFolderMemberWait removes a member from a shared folder, polling until
+// the removal completes.
+func (c *Sharing) RemoveFolderMemberWait(in *RemoveFolderMemberInput, opts *PollOptions) error {
+	out, err := c.RemoveFolderMember(in)
+	if err != nil {
+		return err
+	}
+
+	if !out.Async() {
+		return nil
+	}
+
+	return c.PollJob(func() (bool, error) {
+		status, err := c.CheckRemoveMemberJobStatus(out.Job())
+		if err != nil {
+			return false, err
+		}
+		return status.done("removing folder member")
+	}, opts)
+}
+
+// UnshareFolderInput request input. Set LeaveACopy for members to keep a
+// copy of the folder's contents.
+type UnshareFolderInput struct {
+	SharedFolderID string `json:"shared_folder_id"`
+	LeaveACopy     bool   `json:"leave_a_copy"`
+}
+
+// UnshareFolder stops sharing a folder, removing all of its members, so that
+// it may be deleted as a normal folder. Tag is "complete", or "async_job_id"
+// when the job must be polled with CheckJobStatus, or use UnshareFolderWait.
+func (c *Sharing) UnshareFolder(in *UnshareFolderInput) (out *LaunchResult, err error) {
+	body, err := c.call("/sharing/unshare_folder", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// CheckJobStatus returns the status of an asynchronous sharing job, such as
+// an UnshareFolder.
+func (c *Sharing) CheckJobStatus(in *AsyncJobID) (out *SharingJobStatus, err error) {
+	body, err := c.call("/sharing/check_job_status", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// UnshareFolderWait stops sharing a folder, polling until it completes.
+func (c *Sharing) UnshareFolderWait(in *UnshareFolderInput, opts *PollOptions) error {
+	out, err := c.UnshareFolder(in)
+	if err != nil {
+		return err
+	}
+
+	if !out.Async() {
+		return nil
+	}
+
+	return c.PollJob(func() (bool, error) {
+		status, err := c.CheckJobStatus(out.Job())
+		if err != nil {
+			return false, err
+		}
+		return status.done("unsharing folder")
+	}, opts)
+}
@@ -1,7 +1,11 @@
 package dropbox
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,3 +38,366 @@ func TestSharing_ListSharedFolder(t *testing.T) {
 		assert.NotEmpty(t, out.Entries, "output should be non-empty")
 	}
 }
+
+func TestSharing_ListFileMembers(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/sharing/list_file_members", r.URL.Path)
+
+		var in ListFileMembersInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "id:a4ayc_80_OEAAAAAAAAAXw", in.File)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"users": [{
+				"access_type": {".tag": "owner"},
+				"user": {"account_id": "dbid:AAH4f99", "email": "bob@example.com", "display_name": "Robert Smith", "same_team": true},
+				"is_inherited": false
+			}],
+			"groups": [{
+				"access_type": {".tag": "editor"},
+				"group": {"group_name": "Test group", "group_id": "g:e2db7665347abcd600000000001a2b3c", "member_count": 10},
+				"is_inherited": false
+			}],
+			"invitees": [{
+				"access_type": {".tag": "viewer"},
+				"invitee": {".tag": "email", "email": "jessica@example.com"},
+				"is_inherited": false
+			}],
+			"cursor": "ZtkX9_EHj3x7PMkVuFIhwKYXEpwpLwyxp9vMKomUhllil9q7eWiAu"
+		}`))
+	})
+
+	out, err := c.Sharing.ListFileMembers(&ListFileMembersInput{
+		File: "id:a4ayc_80_OEAAAAAAAAAXw",
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, out.Users, 1)
+	assert.Equal(t, Owner, out.Users[0].AccessType.Tag)
+	assert.Equal(t, "bob@example.com", out.Users[0].User.Email)
+
+	assert.Len(t, out.Groups, 1)
+	assert.Equal(t, AccessType(Editor), out.Groups[0].AccessType.Tag)
+	assert.Equal(t, "Test group", out.Groups[0].Group.GroupName)
+
+	assert.Len(t, out.Invitees, 1)
+	assert.Equal(t, AccessType(Viewer), out.Invitees[0].AccessType.Tag)
+	assert.Equal(t, "jessica@example.com", out.Invitees[0].Invitee.Email)
+
+	assert.NotEmpty(t, out.Cursor)
+
+	_, err = c.Sharing.ListFileMembersContinue(&ListFileMembersContinueInput{})
+	assert.Equal(t, ErrEmptyCursor, err)
+}
+
+func TestSharing_AddFileMember(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/sharing/add_file_member", r.URL.Path)
+
+		var in AddFileMemberInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/hello.txt", in.File)
+		assert.Equal(t, AccessType(Viewer), in.AccessLevel)
+		assert.True(t, in.Quiet)
+		assert.Len(t, in.Members, 2)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"member": {".tag": "email", "email": "justin@example.com"}, "result": {".tag": "success"}},
+			{"member": {".tag": "dropbox_id", "dropbox_id": "dbid:AAH4f99"}, "result": {".tag": "member_error", "member_error": {".tag": "invalid_member"}}}
+		]`))
+	})
+
+	out, err := c.Sharing.AddFileMember(&AddFileMemberInput{
+		File: "/hello.txt",
+		Members: []MemberSelector{
+			{Tag: MemberSelectorEmail, Email: "justin@example.com"},
+			{Tag: MemberSelectorDropboxID, DropboxID: "dbid:AAH4f99"},
+		},
+		AccessLevel: Viewer,
+		Quiet:       true,
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, out.Results, 2)
+	assert.Equal(t, "justin@example.com", out.Results[0].Member.Email)
+	assert.Equal(t, "success", out.Results[0].Result.Tag)
+	assert.Equal(t, "dbid:AAH4f99", out.Results[1].Member.DropboxID)
+	assert.Equal(t, "member_error", out.Results[1].Result.Tag)
+	assert.Equal(t, "invalid_member", out.Results[1].Result.MemberError.Tag)
+}
+
+func TestSharing_RemoveFileMember(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/sharing/remove_file_member_2", r.URL.Path)
+
+		var in RemoveFileMemberInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/hello.txt", in.File)
+		assert.Equal(t, MemberSelectorEmail, in.Member.Tag)
+		assert.Equal(t, "justin@example.com", in.Member.Email)
+
+		writeJSON(w, 200, map[string]string{".tag": "success"})
+	})
+
+	out, err := c.Sharing.RemoveFileMember(&RemoveFileMemberInput{
+		File:   "/hello.txt",
+		Member: MemberSelector{Tag: MemberSelectorEmail, Email: "justin@example.com"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", out.Tag)
+}
+
+func TestSharing_GetOrCreateSharedLink_existing(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/sharing/list_shared_links", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"links": [
+			{".tag": "folder", "url": "https://www.dropbox.com/sh/parent", "path_lower": "/docs"},
+			{".tag": "file", "url": "https://www.dropbox.com/s/hello", "path_lower": "/docs/hello.txt"}
+		]}`))
+	})
+
+	link, err := c.Sharing.GetOrCreateSharedLink("/docs/Hello.txt", SharedLinkSettings{})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.dropbox.com/s/hello", link.URL)
+}
+
+func TestSharing_GetOrCreateSharedLink_create(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/sharing/list_shared_links":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"links": [
+				{".tag": "folder", "url": "https://www.dropbox.com/sh/parent", "path_lower": "/docs"}
+			]}`))
+		case "/2/sharing/create_shared_link_with_settings":
+			var in CreateSharedLinkInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "/docs/hello.txt", in.Path)
+			assert.Equal(t, Public, in.Settings.RequestedVisibility)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{".tag": "file", "url": "https://www.dropbox.com/s/hello", "path_lower": "/docs/hello.txt"}`))
+		default:
+			t.Fatalf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	link, err := c.Sharing.GetOrCreateSharedLink("/docs/hello.txt", SharedLinkSettings{
+		RequestedVisibility: Public,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.dropbox.com/s/hello", link.URL)
+}
+
+func TestSharing_ListSharedLinks_directOnly(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		var in ListShareLinksInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "/docs/hello.txt", in.Path)
+
+		links := []map[string]string{
+			{".tag": "file", "url": "https://www.dropbox.com/s/hello", "path_lower": "/docs/hello.txt"},
+		}
+
+		if !in.DirectOnly {
+			links = append(links, map[string]string{".tag": "folder", "url": "https://www.dropbox.com/sh/parent", "path_lower": "/docs"})
+		}
+
+		writeJSON(w, 200, map[string]interface{}{"links": links})
+	})
+
+	out, err := c.Sharing.ListSharedLinks(&ListShareLinksInput{Path: "/docs/hello.txt"})
+	assert.NoError(t, err)
+	assert.Len(t, out.Links, 2)
+
+	out, err = c.Sharing.ListSharedLinks(&ListShareLinksInput{Path: "/docs/hello.txt", DirectOnly: true})
+	assert.NoError(t, err)
+	assert.Len(t, out.Links, 1)
+	assert.Equal(t, "/docs/hello.txt", out.Links[0].PathLower)
+}
+
+func TestSharing_ListSharedFolderContents(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/sharing/get_folder_metadata":
+			var in GetSharedFolderMetadataInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			if in.SharedFolderID == "84528192421" {
+				writeJSON(w, 200, map[string]string{"shared_folder_id": "84528192421", "path_lower": "/team docs"})
+				return
+			}
+
+			writeJSON(w, 200, map[string]string{"shared_folder_id": in.SharedFolderID})
+		case "/2/files/list_folder":
+			var in ListFolderInput
+			assert.NoError(t, decodeArg(r, &in))
+
+			writeJSON(w, 200, map[string]interface{}{
+				"entries":  []map[string]string{{".tag": "file", "path_lower": in.Path + "/a.txt"}},
+				"cursor":   "cursor",
+				"has_more": true,
+			})
+		case "/2/files/list_folder/continue":
+			writeJSON(w, 200, map[string]interface{}{
+				"entries":  []map[string]string{{".tag": "file", "path_lower": "/team docs/b.txt"}},
+				"has_more": false,
+			})
+		}
+	})
+
+	entries, err := c.Sharing.ListSharedFolderContents("84528192421")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "/team docs/a.txt", entries[0].PathLower)
+	assert.Equal(t, "/team docs/b.txt", entries[1].PathLower)
+
+	entries, err = c.Sharing.ListSharedFolderContents("1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "ns:1234/a.txt", entries[0].PathLower)
+}
+
+func TestSharing_CreateSharedLink_settings(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/sharing/create_shared_link_with_settings", r.URL.Path)
+
+		var in map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		assert.Equal(t, map[string]interface{}{
+			"audience":       "team",
+			"access":         "viewer",
+			"allow_download": false,
+			"expires":        "2026-01-01T00:00:00Z",
+		}, in["settings"])
+
+		writeJSON(w, 200, map[string]string{".tag": "file", "url": "https://www.dropbox.com/s/hello", "path_lower": "/hello.txt"})
+	})
+
+	allow := false
+	out, err := c.Sharing.CreateSharedLink(&CreateSharedLinkInput{
+		Path: "/hello.txt",
+		Settings: &SharedLinkSettings{
+			Audience:      LinkAudienceTeam,
+			Access:        LinkAccessViewer,
+			AllowDownload: &allow,
+			Expires:       "2026-01-01T00:00:00Z",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.dropbox.com/s/hello", out.URL)
+}
+
+func TestSharing_RevokeSharedLink(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/sharing/revoke_shared_link", r.URL.Path)
+
+		var in RevokeSharedLinkInput
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+
+		if in.URL == "https://www.dropbox.com/s/gone" {
+			writeError(w, 409, "shared_link_not_found/..")
+			return
+		}
+		writeJSON(w, 200, nil)
+	})
+
+	assert.NoError(t, c.Sharing.RevokeSharedLink(&RevokeSharedLinkInput{"https://www.dropbox.com/s/hello"}))
+	assert.Equal(t, ErrSharedLinkNotFound, c.Sharing.RevokeSharedLink(&RevokeSharedLinkInput{"https://www.dropbox.com/s/gone"}))
+}
+
+func TestSharing_GetSharedLinkFile(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "content.dropboxapi.com", r.URL.Host)
+		assert.Equal(t, "/2/sharing/get_shared_link_file", r.URL.Path)
+
+		var in GetSharedLinkFileInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "https://www.dropbox.com/sh/s6fvw6ol7rmqo1x/AAAgWRSbjmYDvPpDB30Sykjfa", in.URL)
+		assert.Equal(t, "/a.txt", in.Path)
+
+		w.Header().Set("Dropbox-API-Result", `{".tag": "file", "url": "https://www.dropbox.com/sh/s6fvw6ol7rmqo1x/AAAgWRSbjmYDvPpDB30Sykjfa?dl=0", "name": "a.txt", "rev": "a1", "size": 5}`)
+		w.Write([]byte("hello"))
+	})
+
+	out, err := c.Sharing.GetSharedLinkFile(&GetSharedLinkFileInput{
+		URL:  "https://www.dropbox.com/sh/s6fvw6ol7rmqo1x/AAAgWRSbjmYDvPpDB30Sykjfa",
+		Path: "/a.txt",
+	})
+	assert.NoError(t, err)
+	defer out.Body.Close()
+
+	assert.Equal(t, "a.txt", out.Metadata.Name)
+	assert.Equal(t, "a1", out.Metadata.Rev)
+	assert.Equal(t, uint64(5), out.Metadata.Size)
+
+	b, err := ioutil.ReadAll(out.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+}
+
+func TestSharing_ListFolderMembers(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/sharing/list_folder_members", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"users": [{"access_type": {".tag": "owner"}, "user": {"account_id": "dbid:1", "email": "a@example.com"}, "is_inherited": false}],
+			"groups": [],
+			"invitees": [{"access_type": {".tag": "viewer"}, "invitee": {".tag": "email", "email": "b@example.com"}, "is_inherited": false}],
+			"cursor": "cursor"
+		}`))
+	})
+
+	out, err := c.Sharing.ListFolderMembers(&ListFolderMembersInput{SharedFolderID: "84528192421"})
+	assert.NoError(t, err)
+	assert.Equal(t, AccessType("owner"), out.Users[0].AccessType.Tag)
+	assert.Equal(t, "b@example.com", out.Invitees[0].Invitee.Email)
+	assert.Equal(t, "cursor", out.Cursor)
+
+	_, err = c.Sharing.ListFolderMembersContinue(&ListFolderMembersContinueInput{})
+	assert.Equal(t, ErrEmptyCursor, err)
+}
+
+func TestSharing_UnshareFolderWait(t *testing.T) {
+	checks := 0
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+
+		switch r.URL.Path {
+		case "/2/sharing/remove_folder_member":
+			assert.JSONEq(t, `{"shared_folder_id": "84528192421", "member": {".tag": "email", "email": "b@example.com"}, "leave_a_copy": true}`, string(b))
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "remove"})
+		case "/2/sharing/check_remove_member_job_status":
+			writeJSON(w, 200, map[string]interface{}{".tag": "failed", "failed": map[string]string{".tag": "no_permission"}})
+		case "/2/sharing/unshare_folder":
+			assert.JSONEq(t, `{"shared_folder_id": "84528192421", "leave_a_copy": false}`, string(b))
+			writeJSON(w, 200, map[string]string{".tag": "async_job_id", "async_job_id": "unshare"})
+		case "/2/sharing/check_job_status":
+			assert.JSONEq(t, `{"async_job_id": "unshare"}`, string(b))
+			if checks++; checks == 1 {
+				writeJSON(w, 200, map[string]string{".tag": "in_progress"})
+				return
+			}
+			writeJSON(w, 200, map[string]string{".tag": "complete"})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	opts := &PollOptions{Interval: time.Millisecond}
+
+	err := c.Sharing.RemoveFolderMemberWait(&RemoveFolderMemberInput{
+		SharedFolderID: "84528192421",
+		Member:         MemberSelector{Tag: MemberSelectorEmail, Email: "b@example.com"},
+		LeaveACopy:     true,
+	}, opts)
+	assert.EqualError(t, err, "dropbox: removing folder member: no_permission")
+
+	err = c.Sharing.UnshareFolderWait(&UnshareFolderInput{SharedFolderID: "84528192421"}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, checks)
+}
@@ -0,0 +1,149 @@
+package dropbox
+
+import (
+	"time"
+)
+
+// Team client for Dropbox Business teams, which requires a team access token.
+type Team struct {
+	*Client
+}
+
+// NewTeam client.
+func NewTeam(config *Config) *Team {
+	return &Team{
+		Client: &Client{
+			Config: config,
+		},
+	}
+}
+
+// TeamMemberProfile describes a member of the team.
+type TeamMemberProfile struct {
+	TeamMemberID  string `json:"team_member_id"`
+	ExternalID    string `json:"external_id,omitempty"`
+	AccountID     string `json:"account_id,omitempty"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Status        struct {
+		Tag string `json:".tag"`
+	} `json:"status"`
+	Name           Name `json:"name"`
+	MembershipType struct {
+		Tag string `json:".tag"`
+	} `json:"membership_type"`
+	JoinedOn     time.Time `json:"joined_on,omitempty"`
+	PersistentID string    `json:"persistent_id,omitempty"`
+}
+
+// TeamMemberInfo is a member's profile and role, where the role Tag is
+// "team_admin", "user_management_admin", "support_admin" or "member_only".
+type TeamMemberInfo struct {
+	Profile TeamMemberProfile `json:"profile"`
+	Role    struct {
+		Tag string `json:".tag"`
+	} `json:"role"`
+}
+
+// MembersListInput request input.
+type MembersListInput struct {
+	Limit          uint64 `json:"limit,omitempty"`
+	IncludeRemoved bool   `json:"include_removed,omitempty"`
+}
+
+// MembersListOutput request output. When HasMore is true the next page is
+// returned by MembersListContinue with the Cursor.
+type MembersListOutput struct {
+	Members []*TeamMemberInfo `json:"members"`
+	Cursor  string            `json:"cursor"`
+	HasMore bool              `json:"has_more"`
+}
+
+// MembersList returns the first page of the team's members.
+func (c *Team) MembersList(in *MembersListInput) (out *MembersListOutput, err error) {
+	body, err := c.call("/team/members/list", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// MembersListContinueInput request input.
+type MembersListContinueInput struct {
+	Cursor string `json:"cursor"`
+}
+
+// MembersListContinue returns the next page of the team's members,
+// returning ErrEmptyCursor when there is no cursor.
+func (c *Team) MembersListContinue(in *MembersListContinueInput) (out *MembersListOutput, err error) {
+	if in.Cursor == "" {
+		return nil, ErrEmptyCursor
+	}
+
+	body, err := c.call("/team/members/list/continue", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
+
+// MembersListAll returns every member of the team, following the cursor
+// until there are no more.
+func (c *Team) MembersListAll(in *MembersListInput) ([]*TeamMemberInfo, error) {
+	out, err := c.MembersList(in)
+	if err != nil {
+		return nil, err
+	}
+
+	members := out.Members
+
+	for out.HasMore {
+		if out, err = c.MembersListContinue(&MembersListContinueInput{out.Cursor}); err != nil {
+			return members, err
+		}
+		members = append(members, out.Members...)
+	}
+
+	return members, nil
+}
+
+// UserSelector identifies a team member by one of its fields, where Tag is
+// "team_member_id", "external_id" or "email".
+type UserSelector struct {
+	Tag          string `json:".tag"`
+	TeamMemberID string `json:"team_member_id,omitempty"`
+	ExternalID   string `json:"external_id,omitempty"`
+	Email        string `json:"email,omitempty"`
+}
+
+// MembersGetInfoInput request input.
+type MembersGetInfoInput struct {
+	Members []UserSelector `json:"members"`
+}
+
+// MembersGetInfoItem is the result for one member, where Tag is
+// "member_info", or "id_not_found" with the IDNotFound.
+type MembersGetInfoItem struct {
+	Tag        string `json:".tag"`
+	IDNotFound string `json:"id_not_found,omitempty"`
+	TeamMemberInfo
+}
+
+// MembersGetInfo returns information about the given members, in the same
+// order.
+func (c *Team) MembersGetInfo(in *MembersGetInfoInput) (out []*MembersGetInfoItem, err error) {
+	body, err := c.call("/team/members/get_info", in)
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	err = decode(body, &out)
+	return
+}
@@ -0,0 +1,84 @@
+package dropbox
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeam_MembersListAll(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2/team/members/list":
+			var in MembersListInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, uint64(1), in.Limit)
+
+			writeJSON(w, 200, map[string]interface{}{
+				"members": []interface{}{
+					map[string]interface{}{
+						"profile": map[string]interface{}{"team_member_id": "dbmid:a", "email": "a@example.com", "status": map[string]string{".tag": "active"}},
+						"role":    map[string]string{".tag": "team_admin"},
+					},
+				},
+				"cursor":   "c1",
+				"has_more": true,
+			})
+		case "/2/team/members/list/continue":
+			var in MembersListContinueInput
+			assert.NoError(t, decodeArg(r, &in))
+			assert.Equal(t, "c1", in.Cursor)
+
+			writeJSON(w, 200, map[string]interface{}{
+				"members": []interface{}{
+					map[string]interface{}{
+						"profile": map[string]interface{}{"team_member_id": "dbmid:b", "email": "b@example.com"},
+						"role":    map[string]string{".tag": "member_only"},
+					},
+				},
+				"cursor":   "c2",
+				"has_more": false,
+			})
+		}
+	})
+
+	members, err := c.Team.MembersListAll(&MembersListInput{Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+	assert.Equal(t, "dbmid:a", members[0].Profile.TeamMemberID)
+	assert.Equal(t, "active", members[0].Profile.Status.Tag)
+	assert.Equal(t, "team_admin", members[0].Role.Tag)
+	assert.Equal(t, "b@example.com", members[1].Profile.Email)
+
+	_, err = c.Team.MembersListContinue(&MembersListContinueInput{})
+	assert.Equal(t, ErrEmptyCursor, err)
+}
+
+func TestTeam_MembersGetInfo(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/team/members/get_info", r.URL.Path)
+
+		var in MembersGetInfoInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, []UserSelector{{Tag: "email", Email: "a@example.com"}, {Tag: "team_member_id", TeamMemberID: "dbmid:x"}}, in.Members)
+
+		writeJSON(w, 200, []interface{}{
+			map[string]interface{}{
+				".tag":    "member_info",
+				"profile": map[string]interface{}{"team_member_id": "dbmid:a", "email": "a@example.com"},
+				"role":    map[string]string{".tag": "member_only"},
+			},
+			map[string]string{".tag": "id_not_found", "id_not_found": "dbmid:x"},
+		})
+	})
+
+	out, err := c.Team.MembersGetInfo(&MembersGetInfoInput{
+		Members: []UserSelector{{Tag: "email", Email: "a@example.com"}, {Tag: "team_member_id", TeamMemberID: "dbmid:x"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, out, 2)
+	assert.Equal(t, "dbmid:a", out[0].Profile.TeamMemberID)
+	assert.Equal(t, "id_not_found", out[1].Tag)
+	assert.Equal(t, "dbmid:x", out[1].IDNotFound)
+}
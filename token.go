@@ -0,0 +1,93 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// tokenPath is the path of the OAuth2 token endpoint on the API host.
+const tokenPath = "/oauth2/token"
+
+// accessToken returns the current access token.
+func (c *Config) accessToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.AccessToken
+}
+
+// canRefresh returns whether the access token can be refreshed.
+func (c *Config) canRefresh() bool {
+	return c.RefreshToken != "" && c.AppKey != ""
+}
+
+// tokenError is the error response of the token endpoint.
+type tokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Error string.
+func (e *tokenError) Error() string {
+	return fmt.Sprintf("dropbox: refreshing access token: %s: %s", e.Code, e.Description)
+}
+
+// refresh obtains a new access token, unless it has changed from expired
+// since the request using it was made, such as by a concurrent refresh.
+func (c *Config) refresh(req *http.Request, expired string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.AccessToken != expired {
+		return nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {c.RefreshToken},
+		"client_id":     {c.AppKey},
+	}
+
+	if c.AppSecret != "" {
+		form.Set("client_secret", c.AppSecret)
+	}
+
+	r, err := http.NewRequest("POST", c.baseURL()+tokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	r = r.WithContext(req.Context())
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := c.httpClient().Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		e := &tokenError{Code: http.StatusText(res.StatusCode)}
+		json.NewDecoder(res.Body).Decode(e)
+		return e
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return err
+	}
+
+	c.AccessToken = token.AccessToken
+
+	if c.OnTokenRefresh != nil {
+		c.OnTokenRefresh(token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn)*time.Second))
+	}
+
+	return nil
+}
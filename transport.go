@@ -0,0 +1,67 @@
+package dropbox
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// notifyHost is the host of the unauthenticated notify endpoints.
+const notifyHost = "notify.dropboxapi.com"
+
+// notifyRequest is the context key marking requests to the notify endpoints,
+// whose host may be overridden with the Config's NotifyURL.
+type notifyRequest struct{}
+
+// isNotify returns whether req is to a notify endpoint.
+func isNotify(req *http.Request) bool {
+	return req.URL.Host == notifyHost || req.Context().Value(notifyRequest{}) != nil
+}
+
+// Transport is an http.RoundTripper which adds the Dropbox authorization and
+// common headers from Config to each request, for use with your own HTTP
+// client or transport stack. Requests to the notify endpoints are sent
+// without authorization, as Dropbox requires.
+type Transport struct {
+	Config *Config
+
+	// Base is the underlying transport, defaulting to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip implementation.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context())
+	t.Config.setHeaders(req)
+	return base.RoundTrip(req)
+}
+
+// setHeaders adds the authorization and common headers to req.
+func (c *Config) setHeaders(req *http.Request) {
+	notify := isNotify(req)
+
+	if !notify {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken())
+	}
+
+	if c.PathRoot != nil && !notify {
+		root, _ := json.Marshal(c.PathRoot)
+		req.Header.Set("Dropbox-API-Path-Root", string(root))
+	}
+
+	if c.SelectUser != "" && !notify {
+		req.Header.Set("Dropbox-API-Select-User", c.SelectUser)
+	}
+
+	if c.SelectAdmin != "" && !notify {
+		req.Header.Set("Dropbox-API-Select-Admin", c.SelectAdmin)
+	}
+
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+}
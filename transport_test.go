@@ -0,0 +1,41 @@
+package dropbox
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransport(t *testing.T) {
+	var got *http.Request
+
+	config := NewConfig("token")
+	config.UserAgent = "app/1.0"
+
+	client := &http.Client{
+		Transport: &Transport{
+			Config: config,
+			Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				got = r
+				return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+			}),
+		},
+	}
+
+	req, err := http.NewRequest("POST", "https://api.dropboxapi.com/2/files/list_folder", nil)
+	assert.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token", got.Header.Get("Authorization"))
+	assert.Equal(t, "app/1.0", got.Header.Get("User-Agent"))
+	assert.Empty(t, req.Header.Get("Authorization"), "original request should not be modified")
+
+	req, err = http.NewRequest("POST", "https://notify.dropboxapi.com/2/files/list_folder/longpoll", nil)
+	assert.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Empty(t, got.Header.Get("Authorization"))
+}
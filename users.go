@@ -1,9 +1,5 @@
 package dropbox
 
-import (
-	"encoding/json"
-)
-
 // Users client for user accounts.
 type Users struct {
 	*Client
@@ -23,15 +19,28 @@ type GetAccountInput struct {
 	AccountID string `json:"account_id"`
 }
 
+// Name of a user.
+type Name struct {
+	GivenName    string `json:"given_name"`
+	Surname      string `json:"surname"`
+	FamiliarName string `json:"familiar_name"`
+	DisplayName  string `json:"display_name"`
+}
+
+// BasicAccount is the information about a user's account visible to others.
+type BasicAccount struct {
+	AccountID       string `json:"account_id"`
+	Name            Name   `json:"name"`
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Disabled        bool   `json:"disabled"`
+	IsTeammate      bool   `json:"is_teammate"`
+	ProfilePhotoURL string `json:"profile_photo_url,omitempty"`
+}
+
 // GetAccountOutput request output.
 type GetAccountOutput struct {
-	AccountID string `json:"account_id"`
-	Name      struct {
-		GivenName    string `json:"given_name"`
-		Surname      string `json:"surname"`
-		FamiliarName string `json:"familiar_name"`
-		DisplayName  string `json:"display_name"`
-	} `json:"name"`
+	BasicAccount
 }
 
 // GetAccount returns information about a user's account.
@@ -42,19 +51,14 @@ func (c *Users) GetAccount(in *GetAccountInput) (out *GetAccountOutput, err erro
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
 // GetCurrentAccountOutput request output.
 type GetCurrentAccountOutput struct {
-	AccountID string `json:"account_id"`
-	Name      struct {
-		GivenName    string `json:"given_name"`
-		Surname      string `json:"surname"`
-		FamiliarName string `json:"familiar_name"`
-		DisplayName  string `json:"display_name"`
-	} `json:"name"`
+	AccountID    string `json:"account_id"`
+	Name         Name   `json:"name"`
 	Email        string `json:"email"`
 	Locale       string `json:"locale"`
 	ReferralLink string `json:"referral_link"`
@@ -62,7 +66,13 @@ type GetCurrentAccountOutput struct {
 	AccountType  struct {
 		Tag string `json:".tag"`
 	} `json:"account_type"`
-	Country string `json:"country"`
+	Country  string `json:"country"`
+	RootInfo struct {
+		Tag             string `json:".tag"`
+		RootNamespaceID string `json:"root_namespace_id"`
+		HomeNamespaceID string `json:"home_namespace_id"`
+		HomePath        string `json:"home_path,omitempty"`
+	} `json:"root_info"`
 }
 
 // GetCurrentAccount returns information about the current user's account.
@@ -73,17 +83,61 @@ func (c *Users) GetCurrentAccount() (out *GetCurrentAccountOutput, err error) {
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
 
+// SpaceAllocation is the space allocated to the user. Tag is "individual",
+// where Allocated is the user's quota, or "team", where Used and Allocated
+// are for the whole team and the UserWithinTeamSpace fields describe any
+// limit on the user's share of it.
+type SpaceAllocation struct {
+	Tag       string `json:".tag"`
+	Used      uint64 `json:"used"`
+	Allocated uint64 `json:"allocated"`
+
+	UserWithinTeamSpaceAllocated  uint64 `json:"user_within_team_space_allocated,omitempty"`
+	UserWithinTeamSpaceUsedCached uint64 `json:"user_within_team_space_used_cached,omitempty"`
+	UserWithinTeamSpaceLimitType  struct {
+		Tag string `json:".tag"`
+	} `json:"user_within_team_space_limit_type"`
+}
+
+// Remaining returns the bytes the user may still use. For a team member with
+// a limit on their share this is the lesser of what remains of their limit
+// and of the team's space.
+func (a *SpaceAllocation) Remaining(used uint64) uint64 {
+	remaining := sub(a.Allocated, used)
+
+	if a.Tag == "team" {
+		remaining = sub(a.Allocated, a.Used)
+		if a.UserWithinTeamSpaceAllocated > 0 {
+			if r := sub(a.UserWithinTeamSpaceAllocated, used); r < remaining {
+				remaining = r
+			}
+		}
+	}
+
+	return remaining
+}
+
+// sub returns a-b, or zero if b is greater.
+func sub(a, b uint64) uint64 {
+	if b > a {
+		return 0
+	}
+	return a - b
+}
+
 // GetSpaceUsageOutput request output.
 type GetSpaceUsageOutput struct {
-	Used       uint64 `json:"used"`
-	Allocation struct {
-		Used      uint64 `json:"used"`
-		Allocated uint64 `json:"allocated"`
-	} `json:"allocation"`
+	Used       uint64          `json:"used"`
+	Allocation SpaceAllocation `json:"allocation"`
+}
+
+// Remaining returns the bytes the user may still use.
+func (o *GetSpaceUsageOutput) Remaining() uint64 {
+	return o.Allocation.Remaining(o.Used)
 }
 
 // GetSpaceUsage returns space usage information for the current user's account.
@@ -94,6 +148,6 @@ func (c *Users) GetSpaceUsage() (out *GetSpaceUsageOutput, err error) {
 	}
 	defer body.Close()
 
-	err = json.NewDecoder(body).Decode(&out)
+	err = decode(body, &out)
 	return
 }
@@ -1,6 +1,7 @@
 package dropbox
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,3 +12,63 @@ func TestUsers_GetCurrentAccount(t *testing.T) {
 	_, err := c.Users.GetCurrentAccount()
 	assert.NoError(t, err)
 }
+
+func TestUsers_GetAccount(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2/users/get_account", r.URL.Path)
+
+		var in GetAccountInput
+		assert.NoError(t, decodeArg(r, &in))
+		assert.Equal(t, "dbid:1", in.AccountID)
+
+		writeJSON(w, 200, map[string]interface{}{
+			"account_id":     "dbid:1",
+			"name":           map[string]string{"display_name": "Tobi Ferret"},
+			"email":          "tobi@example.com",
+			"email_verified": true,
+			"is_teammate":    true,
+		})
+	})
+
+	out, err := c.Users.GetAccount(&GetAccountInput{AccountID: "dbid:1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Tobi Ferret", out.Name.DisplayName)
+	assert.Equal(t, "tobi@example.com", out.Email)
+	assert.True(t, out.EmailVerified)
+	assert.True(t, out.IsTeammate)
+	assert.False(t, out.Disabled)
+}
+
+func TestUsers_GetSpaceUsage(t *testing.T) {
+	allocation := map[string]interface{}{}
+
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, 200, map[string]interface{}{"used": 300, "allocation": allocation})
+	})
+
+	allocation = map[string]interface{}{".tag": "individual", "allocated": 1000}
+	out, err := c.Users.GetSpaceUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, "individual", out.Allocation.Tag)
+	assert.Equal(t, uint64(1000), out.Allocation.Allocated)
+	assert.Equal(t, uint64(700), out.Remaining())
+
+	allocation = map[string]interface{}{
+		".tag":                              "team",
+		"used":                              9000,
+		"allocated":                         10000,
+		"user_within_team_space_allocated":  500,
+		"user_within_team_space_limit_type": map[string]string{".tag": "stop_sync"},
+	}
+	out, err = c.Users.GetSpaceUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, "team", out.Allocation.Tag)
+	assert.Equal(t, uint64(9000), out.Allocation.Used)
+	assert.Equal(t, "stop_sync", out.Allocation.UserWithinTeamSpaceLimitType.Tag)
+	assert.Equal(t, uint64(200), out.Remaining())
+
+	allocation["user_within_team_space_allocated"] = 0
+	out, err = c.Users.GetSpaceUsage()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), out.Remaining())
+}
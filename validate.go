@@ -0,0 +1,147 @@
+package dropbox
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// uploadMaxSize is the largest file which may be sent with Upload.
+const uploadMaxSize = 150 << 20
+
+// listFolderMaxLimit is the largest page size of ListFolder.
+const listFolderMaxLimit = 2000
+
+// DryRunError is returned for every request when Config.DryRun is set,
+// describing the request which would have been sent.
+type DryRunError struct {
+	Method string
+	URL    string
+
+	// Header of the request, with the access token redacted. The arguments
+	// of content requests are in the Dropbox-API-Arg header.
+	Header http.Header
+
+	// Body of rpc requests.
+	Body []byte
+}
+
+// Error string.
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("dropbox: dry run of %s %s", e.Method, e.URL)
+}
+
+// dryRun returns the DryRunError of req.
+func dryRun(req *http.Request) *DryRunError {
+	return &DryRunError{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: redact(req.Header),
+		Body:   requestBody(req),
+	}
+}
+
+// validatePath returns an error if p is malformed, or is the root when a
+// file or folder is required.
+func validatePath(p string, root bool) error {
+	p, err := NormalizePath(p)
+	if err != nil {
+		return err
+	}
+
+	if p == "" && !root {
+		return fmt.Errorf("dropbox: the root folder cannot be used here")
+	}
+
+	return nil
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request: the Path must be a file, the Mode supported, and a Reader of
+// known size no larger than Upload's 150MB limit.
+func (in *UploadInput) Validate() error {
+	if err := validatePath(in.Path, false); err != nil {
+		return err
+	}
+
+	switch {
+	case in.Mode == "", in.Mode == WriteModeAdd, in.Mode == WriteModeOverwrite, in.Mode.Rev() != "":
+	default:
+		return fmt.Errorf("dropbox: unsupported write mode %q", in.Mode)
+	}
+
+	if in.ClientModified != "" {
+		if _, err := time.Parse(time.RFC3339Nano, in.ClientModified); err != nil {
+			return fmt.Errorf("dropbox: invalid client_modified %q", in.ClientModified)
+		}
+	}
+
+	if in.Reader == nil {
+		return fmt.Errorf("dropbox: upload of %s has no Reader", in.Path)
+	}
+
+	size, err := readerSize(in.Reader)
+	if err != nil {
+		return err
+	}
+
+	if size > uploadMaxSize {
+		return fmt.Errorf("dropbox: upload of %s is %d bytes, larger than 150MB, use UploadLarge", in.Path, size)
+	}
+
+	return nil
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request.
+func (in *DownloadInput) Validate() error {
+	if in.Start < 0 || in.Length < 0 {
+		return fmt.Errorf("dropbox: invalid range of %s", in.Path)
+	}
+	return validatePath(in.Path, false)
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request.
+func (in *GetMetadataInput) Validate() error {
+	return validatePath(in.Path, false)
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request.
+func (in *ListFolderInput) Validate() error {
+	if in.Limit > listFolderMaxLimit {
+		return fmt.Errorf("dropbox: limit %d exceeds %d", in.Limit, listFolderMaxLimit)
+	}
+	return validatePath(in.Path, true)
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request.
+func (in *CreateFolderInput) Validate() error {
+	return validatePath(in.Path, false)
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request.
+func (in *DeleteInput) Validate() error {
+	return validatePath(in.Path, false)
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request.
+func (in *CopyInput) Validate() error {
+	if err := validatePath(in.FromPath, false); err != nil {
+		return err
+	}
+	return validatePath(in.ToPath, false)
+}
+
+// Validate returns an error if the input is malformed, without making a
+// request.
+func (in *MoveInput) Validate() error {
+	if err := validatePath(in.FromPath, false); err != nil {
+		return err
+	}
+	return validatePath(in.ToPath, false)
+}
@@ -0,0 +1,50 @@
+package dropbox
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadInput_Validate(t *testing.T) {
+	r := bytes.NewReader([]byte("hello"))
+
+	assert.NoError(t, (&UploadInput{Path: "/a.txt", Reader: r}).Validate())
+	assert.NoError(t, (&UploadInput{Path: "/a.txt", Mode: WriteModeUpdate("a1"), Reader: r}).Validate())
+
+	assert.IsType(t, &InvalidPathError{}, (&UploadInput{Path: "a.txt", Reader: r}).Validate())
+	assert.Error(t, (&UploadInput{Path: "/", Reader: r}).Validate())
+	assert.EqualError(t, (&UploadInput{Path: "/a.txt", Mode: "replace", Reader: r}).Validate(), `dropbox: unsupported write mode "replace"`)
+	assert.Error(t, (&UploadInput{Path: "/a.txt"}).Validate())
+	assert.Error(t, (&UploadInput{Path: "/a.txt", ClientModified: "yesterday", Reader: r}).Validate())
+
+	big := bytes.NewReader(make([]byte, uploadMaxSize+1))
+	assert.Contains(t, (&UploadInput{Path: "/a.txt", Reader: big}).Validate().Error(), "use UploadLarge")
+}
+
+func TestInput_Validate(t *testing.T) {
+	assert.NoError(t, (&ListFolderInput{Path: ""}).Validate())
+	assert.NoError(t, (&ListFolderInput{Path: "id:a4ayc_80_OEAAAAAAAAAXw"}).Validate())
+	assert.Error(t, (&ListFolderInput{Path: "", Limit: 2001}).Validate())
+	assert.Error(t, (&GetMetadataInput{Path: ""}).Validate())
+	assert.Error(t, (&DownloadInput{Path: "/a.txt", Start: -1}).Validate())
+	assert.Error(t, (&MoveInput{FromPath: "/a.txt", ToPath: "b.txt"}).Validate())
+	assert.NoError(t, (&CopyInput{FromPath: "/a.txt", ToPath: "/b.txt"}).Validate())
+}
+
+func TestConfig_DryRun(t *testing.T) {
+	c := mockClient(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("should not send requests")
+	})
+	c.DryRun = true
+
+	_, err := c.Files.CreateFolder(&CreateFolderInput{Path: "/a"})
+	e, ok := err.(*DryRunError)
+	assert.True(t, ok)
+	assert.Equal(t, "https://api.dropboxapi.com/2/files/create_folder", e.URL)
+	assert.Equal(t, "Bearer REDACTED", e.Header.Get("Authorization"))
+	assert.JSONEq(t, `{"path": "/a"}`, string(e.Body))
+	assert.Equal(t, "dropbox: dry run of POST https://api.dropboxapi.com/2/files/create_folder", e.Error())
+}